@@ -0,0 +1,75 @@
+package _115
+
+import (
+	"context"
+	"net/url"
+	"regexp"
+	"strings"
+
+	driver115 "github.com/SheltonZhu/115driver/pkg/driver"
+	"github.com/alist-org/alist/v3/pkg/utils"
+	"github.com/go-resty/resty/v2"
+)
+
+const defaultAPIDomain = "115.com"
+
+// apiHosts are the hosts of the 115 apis under their domain, the cdn hosts
+// download urls point to are left alone
+var apiHosts = []string{"", "webapi", "web.api", "proapi", "passportapi", "qrcodeapi", "uplb", "lixian", "my", "aps"}
+
+var domainRe = regexp.MustCompile(`^([a-z0-9]([a-z0-9-]*[a-z0-9])?\.)+[a-z]{2,}$`)
+
+// apiDomain is APIDomain when it is a valid domain name, 115.com otherwise
+func (d *Pan115) apiDomain() string {
+	domain := strings.ToLower(strings.TrimSpace(d.APIDomain))
+	if domain == "" {
+		return defaultAPIDomain
+	}
+	if !domainRe.MatchString(domain) {
+		logger(context.Background()).Warnf("api domain %q isn't a domain name, using %s", d.APIDomain, defaultAPIDomain)
+		return defaultAPIDomain
+	}
+	return domain
+}
+
+// apiURL moves rawURL to domain when it is the url of a 115 api, other urls are returned as is
+func apiURL(rawURL, domain string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || domain == "" || domain == defaultAPIDomain {
+		return rawURL
+	}
+	sub, ok := strings.CutSuffix(u.Hostname(), defaultAPIDomain)
+	if !ok || !utils.SliceContains(apiHosts, strings.TrimSuffix(sub, ".")) {
+		return rawURL
+	}
+	u.Host = sub + domain
+	if port := u.Port(); port != "" {
+		u.Host += ":" + port
+	}
+	return u.String()
+}
+
+// applyAPIDomain sends the api requests of client, the ones the 115driver library builds included,
+// to domain
+func applyAPIDomain(client *driver115.Pan115Client, domain string) {
+	if domain == defaultAPIDomain {
+		return
+	}
+	client.Client.OnBeforeRequest(func(_ *resty.Client, r *resty.Request) error {
+		r.URL = apiURL(r.URL, domain)
+		return nil
+	})
+}
+
+// importDomainCookies has the cookies of cr sent to the api domain when it isn't 115.com
+func importDomainCookies(client *driver115.Pan115Client, cr *driver115.Credential, domain string) {
+	if domain == defaultAPIDomain {
+		return
+	}
+	client.ImportCookies(map[string]string{
+		driver115.CookieNameUid:  cr.UID,
+		driver115.CookieNameCid:  cr.CID,
+		driver115.CookieNameSeid: cr.SEID,
+		driver115.CookieNameKid:  cr.KID,
+	}, "."+domain)
+}
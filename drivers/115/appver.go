@@ -1,6 +1,9 @@
 package _115
 
 import (
+	"fmt"
+	"regexp"
+
 	driver115 "github.com/SheltonZhu/115driver/pkg/driver"
 	"github.com/alist-org/alist/v3/drivers/base"
 	log "github.com/sirupsen/logrus"
@@ -11,6 +14,9 @@ var (
 	appVer  = "27.0.5.7"
 )
 
+// appVerRe matches versions like 27.0.5.7 of the 115 clients
+var appVerRe = regexp.MustCompile(`^\d+(\.\d+){1,3}$`)
+
 func (d *Pan115) getAppVersion() ([]driver115.AppVersion, error) {
 	result := driver115.VersionResp{}
 	resp, err := base.RestyClient.R().Get(driver115.ApiGetVersion)
@@ -41,3 +47,18 @@ func (d *Pan115) getAppVer() string {
 func (d *Pan115) initAppVer() {
 	appVer = d.getAppVer()
 }
+
+// appVersion is the version the storage reports to 115, AppVersionOverride when set
+func (d *Pan115) appVersion() string {
+	if d.AppVersionOverride != "" {
+		return d.AppVersionOverride
+	}
+	return appVer
+}
+
+func checkAppVersion(ver string) error {
+	if ver != "" && !appVerRe.MatchString(ver) {
+		return fmt.Errorf("app version %q should look like %s", ver, appVer)
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package _115
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/pkg/errors"
+)
+
+const maxRedirects = 10
+
+// isLoginURL reports whether u is the 115 login page that an invalid session gets sent to
+func isLoginURL(u *url.URL) bool {
+	return u.Host == "passport.115.com" ||
+		u.Query().Get("ct") == "login" ||
+		strings.HasSuffix(strings.TrimSuffix(u.Path, "/"), "/login")
+}
+
+// checkLoginRedirect maps a redirect to the login page to ErrAuthExpired
+func checkLoginRedirect(resp *http.Response) error {
+	if resp == nil || resp.StatusCode < 300 || resp.StatusCode >= 400 {
+		return nil
+	}
+	loc, err := resp.Location()
+	if err != nil || !isLoginURL(loc) {
+		return nil
+	}
+	return errors.Wrapf(ErrAuthExpired, "%s redirected to login", resp.Request.URL.Path)
+}
+
+// loginRedirectPolicy stops at a redirect to the login page, so the redirect itself
+// reaches checkLoginRedirect instead of the login html being parsed as json.
+// Other redirects carry the headers redirectHeaders picks for their host.
+var loginRedirectPolicy = resty.RedirectPolicyFunc(func(req *http.Request, via []*http.Request) error {
+	if isLoginURL(req.URL) {
+		return http.ErrUseLastResponse
+	}
+	if len(via) >= maxRedirects {
+		return errors.Errorf("stopped after %d redirects", maxRedirects)
+	}
+	redirectHeaders(req, via)
+	return nil
+})
+
+// withRelogin runs fn and, with ReloginOnExpire on, logs in again and runs it once more
+// when it fails with ErrAuthExpired
+func (d *Pan115) withRelogin(ctx context.Context, fn func() error) error {
+	start := time.Now()
+	err := fn()
+	if !d.ReloginOnExpire || !errors.Is(err, ErrAuthExpired) {
+		return err
+	}
+	if err := d.relogin(ctx, start); err != nil {
+		return errors.Wrap(ErrAuthExpired, err.Error())
+	}
+	return fn()
+}
+
+// relogin rebuilds the client from the credentials, the calls that found the session
+// expired at the same time log in only once
+func (d *Pan115) relogin(ctx context.Context, since time.Time) error {
+	d.reloginMu.Lock()
+	defer d.reloginMu.Unlock()
+	if d.reloginAt.After(since) {
+		return nil
+	}
+	logger(ctx).Warnf("115 session expired, logging in again")
+	if err := d.login(); err != nil {
+		return err
+	}
+	d.ossTokens.Del(ossTokenKey)
+	d.reloginAt = time.Now()
+	return nil
+}
@@ -0,0 +1,139 @@
+package _115
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	driver115 "github.com/SheltonZhu/115driver/pkg/driver"
+	"github.com/pkg/errors"
+	"golang.org/x/sync/errgroup"
+)
+
+// operations that send many ids in one call, the keys of BatchSizes
+const (
+	batchDelete  = "delete"
+	batchStar    = "star"
+	batchRestore = "restore"
+	batchOffline = "offline_delete"
+)
+
+// defaultBatchSizes is how many ids each operation sends in one call unless BatchSizes says otherwise,
+// 115 doesn't publish its limits so these stay well below the sizes its web client sends
+var defaultBatchSizes = map[string]int{
+	batchDelete:  500,
+	batchStar:    500,
+	batchRestore: 500,
+	batchOffline: 100,
+}
+
+// BatchResult is what an operation split into calls came to
+type BatchResult struct {
+	Done int `json:"done"`
+	// Failed are the ids of the calls that failed
+	Failed []string `json:"failed,omitempty"`
+}
+
+// parseBatchSizes reads sizes like "delete=200,star=1000" over defaultBatchSizes
+func parseBatchSizes(s string) (map[string]int, error) {
+	sizes := maps.Clone(defaultBatchSizes)
+	for _, kv := range strings.Split(s, ",") {
+		if kv = strings.TrimSpace(kv); kv == "" {
+			continue
+		}
+		op, v, _ := strings.Cut(kv, "=")
+		op = strings.TrimSpace(op)
+		if _, ok := defaultBatchSizes[op]; !ok {
+			return nil, fmt.Errorf("batch size of unknown operation %q", op)
+		}
+		size, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil || size <= 0 {
+			return nil, fmt.Errorf("batch size of %s should be a positive number, got %q", op, v)
+		}
+		sizes[op] = size
+	}
+	return sizes, nil
+}
+
+func (d *Pan115) batchSize(op string) int {
+	if size, ok := d.batchSizes[op]; ok {
+		return size
+	}
+	return defaultBatchSizes[op]
+}
+
+// inBatches calls fn with ids split into calls of the batch size of op, BatchConcurrency
+// calls at once. A failed call doesn't stop the others, its ids are reported in the result
+// and the error is the first one.
+func (d *Pan115) inBatches(ctx context.Context, op string, ids []string, fn func(ids []string) error) (*BatchResult, error) {
+	size := d.batchSize(op)
+	res := &BatchResult{}
+	var (
+		mu       sync.Mutex
+		firstErr error
+	)
+	g := errgroup.Group{}
+	g.SetLimit(max(d.BatchConcurrency, 1))
+	for start := 0; start < len(ids); start += size {
+		chunk := ids[start:min(start+size, len(ids))]
+		g.Go(func() error {
+			err := d.WaitLimit(ctx)
+			if err == nil {
+				err = fn(chunk)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				logger(ctx).Warnf("%s of %d items failed: %v", op, len(chunk), err)
+				res.Failed = append(res.Failed, chunk...)
+				if firstErr == nil {
+					firstErr = err
+				}
+				return nil
+			}
+			res.Done += len(chunk)
+			return nil
+		})
+	}
+	_ = g.Wait()
+	if firstErr != nil {
+		return res, errors.Wrapf(firstErr, "%s of %d of %d items failed", op, len(res.Failed), len(ids))
+	}
+	return res, nil
+}
+
+// indexedForm sets ids in form as key[0], key[1]...
+func indexedForm(form url.Values, key string, ids []string) url.Values {
+	for i, id := range ids {
+		form.Set(fmt.Sprintf("%s[%d]", key, i), id)
+	}
+	return form
+}
+
+// postForm posts form to api. The calls of inBatches run at once, so they build requests of
+// their own: the methods of the 115driver client keep theirs in a field shared by every call
+// and can get the request of another one.
+func (d *Pan115) postForm(ctx context.Context, api string, form url.Values) error {
+	result := driver115.BasicResp{}
+	resp, err := d.client.Load().Client.R().
+		SetContext(ctx).
+		SetFormDataFromValues(form).
+		SetResult(&result).
+		ForceContentType("application/json;charset=UTF-8").
+		Post(api)
+	return driver115.CheckErr(err, &result, resp)
+}
+
+// batchRemove deletes fileIDs into the recycle bin
+func (d *Pan115) batchRemove(ctx context.Context, fileIDs []string) (*BatchResult, error) {
+	if err := d.checkProtected(fileIDs...); err != nil {
+		return nil, err
+	}
+	return d.inBatches(ctx, batchDelete, fileIDs, func(ids []string) error {
+		return d.postForm(ctx, driver115.ApiFileDelete, indexedForm(url.Values{}, "fid", ids))
+	})
+}
@@ -0,0 +1,42 @@
+package _115
+
+import (
+	"time"
+
+	"github.com/Xhofe/go-cache"
+)
+
+// newCache makes a cache of the driver, a disabled one never holds anything
+// so every call goes to 115
+func newCache[T any](disabled bool) cache.ICache[T] {
+	if disabled {
+		return noCache[T]{}
+	}
+	return cache.NewMemCache[T]()
+}
+
+type noCache[T any] struct{}
+
+func (noCache[T]) Set(k string, v T, opts ...cache.SetIOption[T]) bool { return true }
+
+func (noCache[T]) Get(k string) (v T, ok bool) { return }
+
+func (noCache[T]) GetSet(k string, v T, opts ...cache.SetIOption[T]) (old T, ok bool) { return }
+
+func (noCache[T]) GetDel(k string) (v T, ok bool) { return }
+
+func (noCache[T]) Del(keys ...string) int { return 0 }
+
+func (noCache[T]) DelExpired(k string) bool { return false }
+
+func (noCache[T]) Exists(keys ...string) bool { return false }
+
+func (noCache[T]) Expire(k string, d time.Duration) bool { return false }
+
+func (noCache[T]) ExpireAt(k string, t time.Time) bool { return false }
+
+func (noCache[T]) Persist(k string) bool { return false }
+
+func (noCache[T]) Ttl(k string) (time.Duration, bool) { return 0, false }
+
+func (noCache[T]) Clear() {}
@@ -0,0 +1,68 @@
+package _115
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// capabilityTTL is how long probed capabilities are trusted before the next check probes them again
+const capabilityTTL = 24 * time.Hour
+
+type Capabilities struct {
+	VIP bool `json:"vip"`
+	// OfflineDownload is unset for accounts with neither vip nor offline quota left
+	OfflineDownload bool      `json:"offline_download"`
+	OfflineQuota    int64     `json:"offline_quota"`
+	CheckedAt       time.Time `json:"checked_at"`
+}
+
+// ProbeCapabilities asks 115 what the account may use and keeps the answer for the features to check
+func (d *Pan115) ProbeCapabilities(ctx context.Context) (*Capabilities, error) {
+	if err := d.WaitLimit(ctx); err != nil {
+		return nil, err
+	}
+	user, err := d.client.Load().GetUser()
+	if err != nil {
+		return nil, d.maskErr(err)
+	}
+	if err := d.WaitLimit(ctx); err != nil {
+		return nil, err
+	}
+	offline, err := d.client.Load().ListOfflineTask(0)
+	if err != nil {
+		return nil, d.maskErr(err)
+	}
+	caps := &Capabilities{VIP: user.Vip > 0, OfflineQuota: offline.Quota, CheckedAt: time.Now()}
+	caps.OfflineDownload = caps.VIP || caps.OfflineQuota > 0
+	d.capabilities.Store(caps)
+	return caps, nil
+}
+
+// Capabilities are the capabilities probed last, nil when they never were
+func (d *Pan115) Capabilities() *Capabilities {
+	return d.capabilities.Load()
+}
+
+// requireCapability fails with ErrFeatureUnavailable when the probed capabilities say the
+// account can't use feature. Stale capabilities are probed again, and when probing fails
+// nothing is refused so 115 has the last word.
+func (d *Pan115) requireCapability(ctx context.Context, feature string, has func(*Capabilities) bool) error {
+	if !d.CheckCapabilities {
+		return nil
+	}
+	caps := d.capabilities.Load()
+	if caps == nil || time.Since(caps.CheckedAt) > capabilityTTL {
+		fresh, err := d.ProbeCapabilities(ctx)
+		if err != nil {
+			logger(ctx).Warnf("failed to probe the capabilities of the 115 account: %v", err)
+			return nil
+		}
+		caps = fresh
+	}
+	if !has(caps) {
+		return errors.Wrap(ErrFeatureUnavailable, feature)
+	}
+	return nil
+}
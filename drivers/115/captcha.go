@@ -0,0 +1,40 @@
+package _115
+
+import "regexp"
+
+// captchaURL is where 115 shows the slider captcha in a browser, for responses that don't name one
+const captchaURL = "https://captchaapi.115.com/?ac=security_code&type=web"
+
+var urlRe = regexp.MustCompile(`https?://[^\s"'<>]+`)
+
+// SliderCaptchaError is ErrSliderCaptcha with the page the verification can be completed on.
+// It isn't rate limiting, waiting alone doesn't lift it.
+type SliderCaptchaError struct {
+	// Msg is what 115 said
+	Msg string `json:"msg"`
+	URL string `json:"url"`
+}
+
+func (e *SliderCaptchaError) Error() string {
+	msg := ErrSliderCaptcha.Error() + " or at " + e.URL
+	if e.Msg != "" {
+		return e.Msg + ": " + msg
+	}
+	return msg
+}
+
+func (e *SliderCaptchaError) Is(target error) bool {
+	return target == ErrSliderCaptcha
+}
+
+// newSliderCaptcha takes the challenge url from the url field of resp or its message
+func newSliderCaptcha(resp codeResp) error {
+	e := &SliderCaptchaError{Msg: resp.Error, URL: resp.URL}
+	if e.URL == "" {
+		e.URL = urlRe.FindString(resp.Error)
+	}
+	if e.URL == "" {
+		e.URL = captchaURL
+	}
+	return e
+}
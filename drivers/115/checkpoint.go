@@ -0,0 +1,81 @@
+package _115
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+
+	"github.com/alist-org/alist/v3/cmd/flags"
+	"github.com/pkg/errors"
+)
+
+var opIDRegexp = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// checkpoint records the items a long recursive operation is done with, one id per line
+// in a file under the data dir, so the operation restarted with the same id skips them
+type checkpoint struct {
+	mu   sync.Mutex
+	file *os.File
+	done map[string]struct{}
+}
+
+func checkpointPath(opID string) string {
+	return filepath.Join(flags.DataDir, "115_checkpoints", opID)
+}
+
+// openCheckpoint loads the checkpoint of opID, or starts it when there is none
+func openCheckpoint(opID string) (*checkpoint, error) {
+	if !opIDRegexp.MatchString(opID) {
+		return nil, errors.Errorf("invalid operation id %q", opID)
+	}
+	path := checkpointPath(opID)
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	c := &checkpoint{file: f, done: map[string]struct{}{}}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if id := scanner.Text(); id != "" {
+			c.done[id] = struct{}{}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *checkpoint) isDone(id string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.done[id]
+	return ok
+}
+
+func (c *checkpoint) markDone(id string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, err := c.file.WriteString(id + "\n"); err != nil {
+		return err
+	}
+	c.done[id] = struct{}{}
+	return nil
+}
+
+// close keeps the checkpoint for a later run
+func (c *checkpoint) close() error {
+	return c.file.Close()
+}
+
+// finish removes the checkpoint of a completed operation
+func (c *checkpoint) finish() error {
+	_ = c.file.Close()
+	return os.Remove(c.file.Name())
+}
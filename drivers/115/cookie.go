@@ -0,0 +1,63 @@
+package _115
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	driver115 "github.com/SheltonZhu/115driver/pkg/driver"
+	"github.com/pkg/errors"
+)
+
+// staleCookieAge is how old the login in the UID of a cookie gets before the cookie is reported stale
+const staleCookieAge = 90 * 24 * time.Hour
+
+type parsedCookie struct {
+	cr driver115.Credential
+	// extras are the names of the fields that were dropped
+	extras []string
+	// stale lists why the cookie looks outdated
+	stale []string
+}
+
+// parseCookie keeps the UID, CID, SEID and KID of an exported cookie and drops the rest, which
+// would otherwise be sent along and upset the signature or the session. Unlike FromCookie of the
+// library it takes values with "=" in them, empty pairs and a leading "Cookie:".
+// A field exported twice with different values comes from an older session, the last value is used.
+func parseCookie(cookie string, now time.Time) (*parsedCookie, error) {
+	res := &parsedCookie{}
+	fields := map[string]*string{"UID": &res.cr.UID, "CID": &res.cr.CID, "SEID": &res.cr.SEID, "KID": &res.cr.KID}
+	cookie = strings.TrimSpace(cookie)
+	if len(cookie) >= len("cookie:") && strings.EqualFold(cookie[:len("cookie:")], "cookie:") {
+		cookie = cookie[len("cookie:"):]
+	}
+	for _, item := range strings.Split(cookie, ";") {
+		key, value, ok := strings.Cut(strings.TrimSpace(item), "=")
+		if !ok || key == "" {
+			continue
+		}
+		key, value = strings.ToUpper(strings.TrimSpace(key)), strings.TrimSpace(value)
+		field, known := fields[key]
+		if !known {
+			res.extras = append(res.extras, key)
+			continue
+		}
+		if *field != "" && *field != value {
+			res.stale = append(res.stale, fmt.Sprintf("%s is in it twice, an older value is ignored", key))
+		}
+		*field = value
+	}
+	if res.cr.UID == "" || res.cr.CID == "" || res.cr.SEID == "" {
+		return nil, errors.Wrap(driver115.ErrBadCookie, "miss UID, CID or SEID")
+	}
+	// the UID ends with the time of the login, like 12345_A1_1700000000
+	if i := strings.LastIndex(res.cr.UID, "_"); i >= 0 {
+		if ts, err := strconv.ParseInt(res.cr.UID[i+1:], 10, 64); err == nil && ts > 0 {
+			if age := now.Sub(time.Unix(ts, 0)); age > staleCookieAge {
+				res.stale = append(res.stale, fmt.Sprintf("it was logged in %d days ago", int(age.Hours()/24)))
+			}
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,44 @@
+package _115
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	driver115 "github.com/SheltonZhu/115driver/pkg/driver"
+)
+
+func TestParseCookie(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	fresh := fmt.Sprintf("%d", now.Add(-24*time.Hour).Unix())
+	old := fmt.Sprintf("%d", now.Add(-200*24*time.Hour).Unix())
+
+	for _, c := range []struct {
+		name   string
+		cookie string
+		kid    string
+		extras int
+		stale  int
+	}{
+		{"plain", "UID=1_A1_" + fresh + ";CID=c;SEID=s;KID=k", "k", 0, 0},
+		{"extras", "Cookie: UID=1_A1_" + fresh + "; CID=c; SEID=s; KID=k; acw_tc=x=y; USERSESSIONID=u; ;", "k", 2, 0},
+		{"old kid", "UID=1_A1_" + fresh + ";CID=c;SEID=s;KID=old;KID=k", "k", 0, 1},
+		{"old login", "uid=1_A1_" + old + ";cid=c;seid=s", "", 0, 1},
+	} {
+		res, err := parseCookie(c.cookie, now)
+		if err != nil {
+			t.Fatalf("%s: parse failed: %v", c.name, err)
+		}
+		if res.cr.CID != "c" || res.cr.SEID != "s" || res.cr.KID != c.kid {
+			t.Errorf("%s: unexpected credential %+v", c.name, res.cr)
+		}
+		if len(res.extras) != c.extras || len(res.stale) != c.stale {
+			t.Errorf("%s: expect %d extras and %d stale reasons, got %v and %v", c.name, c.extras, c.stale, res.extras, res.stale)
+		}
+	}
+
+	if _, err := parseCookie("CID=c;SEID=s;foo=bar", now); !errors.Is(err, driver115.ErrBadCookie) {
+		t.Errorf("expect %v without a UID, got %v", driver115.ErrBadCookie, err)
+	}
+}
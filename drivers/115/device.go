@@ -0,0 +1,93 @@
+package _115
+
+import (
+	"context"
+
+	driver115 "github.com/SheltonZhu/115driver/pkg/driver"
+	"github.com/pkg/errors"
+)
+
+const apiDeviceLogout = "https://passportapi.115.com/app/1.0/web/1.0/logout/mange"
+
+type Device struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Desc      string `json:"desc"`
+	IP        string `json:"ip"`
+	City      string `json:"city"`
+	LastSeen  int64  `json:"last_seen"`
+	IsCurrent bool   `json:"is_current"`
+	IsUnusual bool   `json:"is_unusual"`
+}
+
+type RevokeResult struct {
+	DeviceID string `json:"device_id"`
+	// ReauthRequired is set when the revoked device was this storage's own session
+	ReauthRequired bool `json:"reauth_required"`
+}
+
+// ListDevices lists the devices logged in to the account, the id of a device is its
+// sso entry which RevokeDevice takes
+func (d *Pan115) ListDevices(ctx context.Context) ([]Device, error) {
+	if err := d.WaitLimit(ctx); err != nil {
+		return nil, err
+	}
+	info, err := d.client.Load().GetInfo()
+	if err != nil {
+		return nil, err
+	}
+	devices := make([]Device, 0, len(info.LoginDevicesInfo.List))
+	for _, dev := range info.LoginDevicesInfo.List {
+		devices = append(devices, Device{
+			ID:        dev.Ssoent,
+			Name:      dev.Name,
+			Desc:      dev.Desc,
+			IP:        dev.IP,
+			City:      dev.City,
+			LastSeen:  int64(dev.Utime),
+			IsCurrent: dev.IsCurrent == 1,
+			IsUnusual: dev.IsUnusual == 1,
+		})
+	}
+	return devices, nil
+}
+
+// RevokeDevice logs deviceID out. Revoking the session of this storage invalidates its
+// cookie, the storage then fails with ErrSessionRevoked until it is logged in again.
+func (d *Pan115) RevokeDevice(ctx context.Context, deviceID string) (*RevokeResult, error) {
+	devices, err := d.ListDevices(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var target *Device
+	for i := range devices {
+		if devices[i].ID == deviceID {
+			target = &devices[i]
+			break
+		}
+	}
+	if target == nil {
+		return nil, errors.Wrapf(driver115.ErrNotExist, "device %s", deviceID)
+	}
+	if err := d.WaitLimit(ctx); err != nil {
+		return nil, err
+	}
+	result := driver115.QRCodeBasicResp{}
+	resp, err := d.client.Load().Client.R().
+		SetContext(ctx).
+		SetFormData(map[string]string{"ssoent": deviceID}).
+		SetResult(&result).
+		ForceContentType("application/json;charset=UTF-8").
+		Post(apiDeviceLogout)
+	if err = driver115.CheckErr(err, &result, resp); err != nil {
+		return nil, err
+	}
+	res := &RevokeResult{DeviceID: deviceID, ReauthRequired: target.IsCurrent}
+	if target.IsCurrent {
+		logger(ctx).Warnf("revoked the session of this storage (%s), update its cookie or QR code token and reload it", target.Name)
+		d.pause.set(ErrSessionRevoked)
+	} else {
+		logger(ctx).Infof("revoked device %s (%s)", target.Name, deviceID)
+	}
+	return res, nil
+}
@@ -0,0 +1,257 @@
+package _115
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"iter"
+	"net/http"
+	"os"
+	stdpath "path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/alist-org/alist/v3/drivers/base"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/stream"
+	"github.com/alist-org/alist/v3/pkg/utils"
+	"github.com/pkg/errors"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	// batchDownloadConcurrency is how many files a batch download fetches at once
+	batchDownloadConcurrency = 4
+	// batchDownloadAttempts is how often a file is tried before it is reported failed
+	batchDownloadAttempts = 3
+)
+
+// errLinkExpired is a download the cdn turned down because its link is no longer signed
+var errLinkExpired = errors.New("download link expired")
+
+type BatchDownloadResult struct {
+	Downloaded int              `json:"downloaded"`
+	Failed     int              `json:"failed"`
+	Files      []DownloadedFile `json:"files"`
+}
+
+type DownloadedFile struct {
+	// Path is relative to the local folder
+	Path  string `json:"path"`
+	Size  int64  `json:"size"`
+	Error string `json:"error,omitempty"`
+}
+
+// BatchDownloadProgress is told how far the download of the file at path got, in percent
+type BatchDownloadProgress func(path string, percentage float64)
+
+type batchSource interface {
+	linker
+	WaitLimit(ctx context.Context) error
+	getNewFile(ctx context.Context, fileID string) (*FileObj, error)
+	ListRecursive(ctx context.Context, dirID string) (iter.Seq2[string, *FileObj], func() error)
+}
+
+// BatchDownloadToLocal downloads fileIDs into localDir on the server, a folder with every file
+// under it in the same structure. Files are fetched a few at once, each tried again with a link
+// signed anew when the cdn finds the old one expired, and read within the server download limit.
+// A file that fails is reported in the result, only a folder that can't be listed fails the batch.
+// localDir isn't checked, requests go through QueueLocalDownload which keeps it in local_download_dir.
+func (d *Pan115) BatchDownloadToLocal(ctx context.Context, fileIDs []string, localDir string, progress BatchDownloadProgress) (*BatchDownloadResult, error) {
+	return batchDownload(withReqID(ctx), d, d.client.Load().Client.GetClient(), fileIDs, localDir, progress)
+}
+
+func batchDownload(ctx context.Context, src batchSource, client *http.Client, fileIDs []string, localDir string, progress BatchDownloadProgress) (*BatchDownloadResult, error) {
+	type job struct {
+		path string
+		file *FileObj
+	}
+	var jobs []job
+	for _, id := range fileIDs {
+		if err := src.WaitLimit(ctx); err != nil {
+			return nil, err
+		}
+		f, err := src.getNewFile(ctx, id)
+		if err != nil {
+			return nil, errors.Wrapf(err, "get info of %s", id)
+		}
+		if !f.IsDir() {
+			jobs = append(jobs, job{path: f.GetName(), file: f})
+			continue
+		}
+		files, errFn := src.ListRecursive(ctx, f.GetID())
+		for path, file := range files {
+			jobs = append(jobs, job{path: stdpath.Join(f.GetName(), path), file: file})
+		}
+		if err := errFn(); err != nil {
+			return nil, errors.Wrapf(err, "list %s", f.GetName())
+		}
+	}
+
+	res := &BatchDownloadResult{Files: make([]DownloadedFile, len(jobs))}
+	if progress != nil {
+		// every file counts from the start, not only the ones being fetched
+		for _, j := range jobs {
+			progress(j.path, 0)
+		}
+	}
+	var mu sync.Mutex
+	g := errgroup.Group{}
+	g.SetLimit(batchDownloadConcurrency)
+	for i, j := range jobs {
+		g.Go(func() error {
+			size, err := downloadToLocal(ctx, src, client, j.file, localDir, j.path, progress)
+			mu.Lock()
+			defer mu.Unlock()
+			res.Files[i] = DownloadedFile{Path: j.path, Size: size}
+			if err != nil {
+				res.Failed++
+				res.Files[i].Error = err.Error()
+				logger(ctx).Warnf("download %s to %s failed: %v", j.path, localDir, err)
+				return nil
+			}
+			res.Downloaded++
+			return nil
+		})
+	}
+	_ = g.Wait()
+	logger(ctx).Infof("downloaded %d files to %s, %d failed", res.Downloaded, localDir, res.Failed)
+	return res, nil
+}
+
+// downloadToLocal downloads file to path under localDir through a .part file. A .part file an
+// interrupted download left behind is picked up where it ends, and is kept when this download
+// fails too. The finished file has to match the SHA-1 115 has for it.
+func downloadToLocal(ctx context.Context, src batchSource, client *http.Client, file *FileObj,
+	localDir, path string, progress BatchDownloadProgress) (int64, error) {
+	if !filepath.IsLocal(filepath.FromSlash(path)) {
+		return 0, fmt.Errorf("%s leaves the local folder", path)
+	}
+	dst := filepath.Join(localDir, filepath.FromSlash(path))
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return 0, err
+	}
+	tmp := dst + ".part"
+	var (
+		link    *model.Link
+		written int64
+	)
+	err := retry(ctx, batchDownloadAttempts, "download "+path, func() (err error) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if link == nil {
+			if link, err = src.Link(ctx, file, model.LinkArgs{Header: http.Header{"User-Agent": []string{base.UserAgent}}}); err != nil {
+				return err
+			}
+		}
+		written, err = fetchTo(ctx, client, link, tmp, file.GetSize(), func(n int64) {
+			if progress != nil && file.GetSize() > 0 {
+				progress(path, float64(n)*100/float64(file.GetSize()))
+			}
+		})
+		if errors.Is(err, errLinkExpired) {
+			link = nil
+		}
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	if expect := file.GetHash().GetHash(utils.SHA1); expect != "" {
+		f, err := os.Open(tmp)
+		if err != nil {
+			return 0, err
+		}
+		actual, err := utils.HashFile(utils.SHA1, f)
+		_ = f.Close()
+		if err != nil {
+			return 0, err
+		}
+		if !strings.EqualFold(actual, expect) {
+			// a bad partial file would fail every resume, start over next time
+			_ = os.Remove(tmp)
+			return 0, fmt.Errorf("downloaded sha1 %s doesn't match %s", actual, expect)
+		}
+	}
+	if err := os.Rename(tmp, dst); err != nil {
+		return 0, err
+	}
+	if progress != nil {
+		progress(path, 100)
+	}
+	return written, nil
+}
+
+// fetchTo downloads link into the file name of size bytes, asking only for the bytes
+// after what name holds already. A server that ignores the range gets the file rewritten.
+func fetchTo(ctx context.Context, client *http.Client, link *model.Link, name string, size int64, written func(n int64)) (int64, error) {
+	out, err := os.OpenFile(name, os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return 0, err
+	}
+	defer out.Close()
+	info, err := out.Stat()
+	if err != nil {
+		return 0, err
+	}
+	have := info.Size()
+	if have > size {
+		// not a part of this file
+		have = 0
+	}
+	if have == size && size > 0 {
+		return size, nil
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, link.URL, nil)
+	if err != nil {
+		return 0, err
+	}
+	for k, v := range link.Header {
+		req.Header[k] = v
+	}
+	if have > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", have))
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusGone:
+		return 0, errors.Wrap(errLinkExpired, resp.Status)
+	case resp.StatusCode >= http.StatusBadRequest:
+		return 0, fmt.Errorf("download answered %s", resp.Status)
+	case have > 0 && resp.StatusCode != http.StatusPartialContent:
+		have = 0
+	case have > 0 && !strings.HasPrefix(resp.Header.Get("Content-Range"), fmt.Sprintf("bytes %d-", have)):
+		return 0, fmt.Errorf("asked for bytes from %d, got %q", have, resp.Header.Get("Content-Range"))
+	}
+	if err := out.Truncate(have); err != nil {
+		return 0, err
+	}
+	if _, err := out.Seek(have, io.SeekStart); err != nil {
+		return 0, err
+	}
+	body := &stream.RateLimitReader{Reader: resp.Body, Limiter: stream.ServerDownloadLimit, Ctx: ctx}
+	n, err := io.Copy(out, &progressReader{Reader: body, n: have, fn: written})
+	if err != nil {
+		return have + n, err
+	}
+	return have + n, out.Close()
+}
+
+type progressReader struct {
+	io.Reader
+	n  int64
+	fn func(n int64)
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.n += int64(n)
+	r.fn(r.n)
+	return n, err
+}
@@ -2,13 +2,17 @@ package _115
 
 import (
 	"context"
-	"strings"
+	"fmt"
+	"net/http"
+	"net/url"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	driver115 "github.com/SheltonZhu/115driver/pkg/driver"
+	"github.com/Xhofe/go-cache"
 	"github.com/alist-org/alist/v3/internal/driver"
 	"github.com/alist-org/alist/v3/internal/model"
-	"github.com/alist-org/alist/v3/pkg/http_range"
 	"github.com/alist-org/alist/v3/pkg/utils"
 	"github.com/pkg/errors"
 	"golang.org/x/time/rate"
@@ -17,13 +21,36 @@ import (
 type Pan115 struct {
 	model.Storage
 	Addition
-	client     *driver115.Pan115Client
+	client     atomic.Pointer[driver115.Pan115Client]
 	limiter    *rate.Limiter
+	throttle   *throttle
 	appVerOnce sync.Once
+	pause      pause
+	ossClock   ossClock
+	reloginMu  sync.Mutex
+	reloginAt  time.Time
+	watchdog   watchdog
+	domain     string
+	masker     *masker
+	batchSizes map[string]int
+	// transport replaces the transport of the clients built by login, tests use it
+	transport http.RoundTripper
+
+	smartFilters []SmartFilter
+	protected    map[string]struct{}
+
+	capabilities atomic.Pointer[Capabilities]
+
+	folderSizes cache.ICache[int64]
+	views       cache.ICache[[]FileObj]
+	listings    cache.ICache[listing]
+	ossTokens   cache.ICache[*driver115.UploadOSSTokenResp]
 }
 
 func (d *Pan115) Config() driver.Config {
-	return config
+	c := config
+	c.NoCache = d.DisableCache
+	return c
 }
 
 func (d *Pan115) GetAddition() driver.Additional {
@@ -31,14 +58,66 @@ func (d *Pan115) GetAddition() driver.Additional {
 }
 
 func (d *Pan115) Init(ctx context.Context) error {
-	d.appVerOnce.Do(d.initAppVer)
+	ctx = withReqID(ctx)
+	if err := checkAppVersion(d.AppVersionOverride); err != nil {
+		return err
+	}
+	var err error
+	if d.batchSizes, err = parseBatchSizes(d.BatchSizes); err != nil {
+		return err
+	}
+	if d.smartFilters, err = parseSmartFolders(d.SmartFolders); err != nil {
+		return err
+	}
+	d.protected = parseProtectedFolders(d.ProtectedFolders)
+	if d.AppVersionOverride == "" {
+		if d.DisableCache {
+			d.initAppVer()
+		} else {
+			d.appVerOnce.Do(d.initAppVer)
+		}
+	}
+	d.pause.reset()
+	d.pause.setDuration(ErrSliderCaptcha, time.Duration(d.CaptchaPause)*time.Minute)
+	d.initCaches()
+	maskers.unregister(d.masker)
+	d.masker = nil
+	if d.MaskCredentials {
+		d.masker = &masker{}
+		maskers.register(d.masker)
+	}
 	if d.LimitRate > 0 {
 		d.limiter = rate.NewLimiter(rate.Limit(d.LimitRate), 1)
 	}
-	return d.login()
+	d.throttle = nil
+	if d.MaxLimitRate > 0 {
+		d.throttle = newThrottle(d.LimitRate, d.MinLimitRate, d.MaxLimitRate)
+		d.limiter = d.throttle.limiter
+	}
+	d.domain = d.apiDomain()
+	if err := d.login(); err != nil {
+		return err
+	}
+	d.capabilities.Store(nil)
+	if d.CheckCapabilities {
+		if _, err := d.ProbeCapabilities(ctx); err != nil {
+			logger(ctx).Warnf("failed to probe the capabilities of the 115 account, features won't be checked before calling 115: %v", err)
+		}
+	}
+	return nil
+}
+
+func (d *Pan115) initCaches() {
+	d.folderSizes = newCache[int64](d.DisableCache)
+	d.views = newCache[[]FileObj](d.DisableCache)
+	d.listings = newCache[listing](d.DisableCache)
+	d.ossTokens = newCache[*driver115.UploadOSSTokenResp](d.DisableCache)
 }
 
 func (d *Pan115) WaitLimit(ctx context.Context) error {
+	if err := d.pause.check(); err != nil {
+		return err
+	}
 	if d.limiter != nil {
 		return d.limiter.Wait(ctx)
 	}
@@ -46,89 +125,190 @@ func (d *Pan115) WaitLimit(ctx context.Context) error {
 }
 
 func (d *Pan115) Drop(ctx context.Context) error {
+	maskers.unregister(d.masker)
+	d.cancelLocalDownloads()
+	d.cancelUploads()
 	return nil
 }
 
-func (d *Pan115) List(ctx context.Context, dir model.Obj, args model.ListArgs) ([]model.Obj, error) {
+func (d *Pan115) List(ctx context.Context, dir model.Obj, args model.ListArgs) (objs []model.Obj, err error) {
+	defer func() { err = d.maskErr(err) }()
+	ctx = withReqID(ctx)
 	if err := d.WaitLimit(ctx); err != nil {
 		return nil, err
 	}
-	files, err := d.getFiles(dir.GetID())
+	var files []FileObj
+	if filter, ok := d.smartFilter(dir.GetID()); ok {
+		err = d.withRelogin(ctx, func() (err error) {
+			files, err = d.ListSmart(ctx, *filter)
+			return err
+		})
+	} else {
+		err = d.withRelogin(ctx, func() (err error) {
+			files, err = d.getFiles(ctx, dir.GetID())
+			return err
+		})
+		if err == nil {
+			d.recordView(dir.GetID(), files)
+		}
+		if dir.GetID() == d.GetRootId() {
+			files = append(files, d.smartFolders()...)
+		}
+	}
 	if err != nil && !errors.Is(err, driver115.ErrNotExist) {
 		return nil, err
 	}
+	if d.ShowFolderSize {
+		for i := range files {
+			if !files[i].IsDir() {
+				continue
+			}
+			if files[i].Size, err = d.ComputeFolderSize(ctx, files[i].GetID()); errors.Is(err, ErrFolderTooLarge) {
+				logger(ctx).Debugf("no size for folder %s: %v", files[i].GetName(), err)
+			} else if err != nil {
+				logger(ctx).Warnf("compute size of folder %s failed: %v", files[i].GetName(), err)
+			}
+		}
+	}
 	return utils.SliceConvert(files, func(src FileObj) (model.Obj, error) {
 		return &src, nil
 	})
 }
 
-func (d *Pan115) Link(ctx context.Context, file model.Obj, args model.LinkArgs) (*model.Link, error) {
+func (d *Pan115) Link(ctx context.Context, file model.Obj, args model.LinkArgs) (link *model.Link, err error) {
+	defer func() { err = d.maskErr(err) }()
+	ctx = withReqID(ctx)
 	if err := d.WaitLimit(ctx); err != nil {
 		return nil, err
 	}
 	userAgent := args.Header.Get("User-Agent")
-	downloadInfo, err := d.
-		DownloadWithUA(file.(*FileObj).PickCode, userAgent)
+	if args.Type == "preview" && d.previewTranscoded(file) {
+		link, err := d.playLink(ctx, file, userAgent)
+		if err == nil {
+			return link, nil
+		}
+		logger(ctx).Debugf("no %s stream of %s, previewing the original: %v", d.PreferredQuality, file.GetName(), err)
+	}
+	var downloadInfo *driver115.DownloadInfo
+	err = d.withRelogin(ctx, func() (err error) {
+		downloadInfo, err = d.DownloadWithUA(ctx, file.(*FileObj).PickCode, userAgent)
+		return err
+	})
+	if errors.Is(err, ErrProtectedContent) && d.PlayProtected && args.Type == "preview" {
+		return d.playLink(ctx, file, userAgent)
+	}
+	if errors.Is(err, ErrUnderReview) {
+		file.(*FileObj).UnderReview = true
+	}
 	if err != nil {
+		logger(ctx).Warnf("get download url of %s failed: %v", file.GetName(), err)
 		return nil, err
 	}
-	link := &model.Link{
+	link = &model.Link{
 		URL:    downloadInfo.Url.Url,
 		Header: downloadInfo.Header,
 	}
+	if d.ResolveRedirects {
+		d.resolveRedirects(ctx, link)
+	}
+	if err := d.checkDownloadScheme(ctx, file.GetName(), link); err != nil {
+		return nil, err
+	}
+	d.applyPlaybackChunk(link)
 	return link, nil
 }
 
-func (d *Pan115) MakeDir(ctx context.Context, parentDir model.Obj, dirName string) (model.Obj, error) {
+func (d *Pan115) MakeDir(ctx context.Context, parentDir model.Obj, dirName string) (obj model.Obj, err error) {
+	defer func() { err = d.maskErr(err) }()
+	ctx = withReqID(ctx)
+	if err := checkWritable(parentDir); err != nil {
+		return nil, err
+	}
 	if err := d.WaitLimit(ctx); err != nil {
 		return nil, err
 	}
 
 	result := driver115.MkdirResp{}
-	form := map[string]string{
-		"pid":   parentDir.GetID(),
-		"cname": dirName,
-	}
-	req := d.client.NewRequest().
-		SetFormData(form).
-		SetResult(&result).
-		ForceContentType("application/json;charset=UTF-8")
-
-	resp, err := req.Post(driver115.ApiDirAdd)
+	err = d.withSanitizedName(ctx, d.normName(dirName), func(name string) error {
+		form := map[string]string{
+			"pid":   parentDir.GetID(),
+			"cname": name,
+		}
+		req := d.client.Load().Client.R().
+			SetContext(ctx).
+			SetFormData(form).
+			SetResult(&result).
+			ForceContentType("application/json;charset=UTF-8")
 
-	err = driver115.CheckErr(err, &result, resp)
+		resp, err := req.Post(driver115.ApiDirAdd)
+		if err == nil {
+			if err := d.checkCodes(ctx, resp.Body(), nameCodes); err != nil {
+				return err
+			}
+		}
+		return driver115.CheckErr(err, &result, resp)
+	})
 	if err != nil {
 		return nil, err
 	}
-	f, err := d.getNewFile(result.FileID)
+	f, err := d.getNewFile(ctx, result.FileID)
 	if err != nil {
 		return nil, nil
 	}
 	return f, nil
 }
 
-func (d *Pan115) Move(ctx context.Context, srcObj, dstDir model.Obj) (model.Obj, error) {
+func (d *Pan115) Move(ctx context.Context, srcObj, dstDir model.Obj) (obj model.Obj, err error) {
+	defer func() { err = d.maskErr(err) }()
+	ctx = withReqID(ctx)
+	if err := checkWritable(srcObj, dstDir); err != nil {
+		return nil, err
+	}
 	if err := d.WaitLimit(ctx); err != nil {
 		return nil, err
 	}
-	if err := d.client.Move(dstDir.GetID(), srcObj.GetID()); err != nil {
+	if err := d.client.Load().Move(dstDir.GetID(), srcObj.GetID()); err != nil {
 		return nil, err
 	}
-	f, err := d.getNewFile(srcObj.GetID())
+	f, err := d.getNewFile(ctx, srcObj.GetID())
 	if err != nil {
 		return nil, nil
 	}
 	return f, nil
 }
 
-func (d *Pan115) Rename(ctx context.Context, srcObj model.Obj, newName string) (model.Obj, error) {
+func (d *Pan115) Rename(ctx context.Context, srcObj model.Obj, newName string) (obj model.Obj, err error) {
+	defer func() { err = d.maskErr(err) }()
+	ctx = withReqID(ctx)
+	if err := checkWritable(srcObj); err != nil {
+		return nil, err
+	}
 	if err := d.WaitLimit(ctx); err != nil {
 		return nil, err
 	}
-	if err := d.client.Rename(srcObj.GetID(), newName); err != nil {
+	if err := d.withSanitizedName(ctx, d.normName(newName), func(name string) error {
+		// the form driver115.Rename sends, made here to see the codes of the response
+		result := driver115.BasicResp{}
+		resp, err := d.client.Load().Client.R().
+			SetContext(ctx).
+			SetFormData(map[string]string{
+				"fid":       srcObj.GetID(),
+				"file_name": name,
+				fmt.Sprintf("files_new_name[%s]", srcObj.GetID()): name,
+			}).
+			SetResult(&result).
+			ForceContentType("application/json;charset=UTF-8").
+			Post(driver115.ApiFileRename)
+		if err == nil {
+			if err := d.checkCodes(ctx, resp.Body(), nameCodes); err != nil {
+				return err
+			}
+		}
+		return driver115.CheckErr(err, &result, resp)
+	}); err != nil {
 		return nil, err
 	}
-	f, err := d.getNewFile((srcObj.GetID()))
+	f, err := d.getNewFile(ctx, (srcObj.GetID()))
 	if err != nil {
 		return nil, nil
 	}
@@ -136,20 +316,47 @@ func (d *Pan115) Rename(ctx context.Context, srcObj model.Obj, newName string) (
 }
 
 func (d *Pan115) Copy(ctx context.Context, srcObj, dstDir model.Obj) error {
+	ctx = withReqID(ctx)
+	if err := checkWritable(dstDir); err != nil {
+		return err
+	}
 	if err := d.WaitLimit(ctx); err != nil {
 		return err
 	}
-	return d.client.Copy(dstDir.GetID(), srcObj.GetID())
+	return d.maskErr(d.client.Load().Copy(dstDir.GetID(), srcObj.GetID()))
 }
 
-func (d *Pan115) Remove(ctx context.Context, obj model.Obj) error {
+func (d *Pan115) Remove(ctx context.Context, obj model.Obj) (err error) {
+	defer func() { err = d.maskErr(err) }()
+	ctx = withReqID(ctx)
+	if err := checkWritable(obj); err != nil {
+		return err
+	}
+	if err := d.checkProtected(obj.GetID()); err != nil {
+		return err
+	}
+	if d.SafeDelete {
+		_, err := d.SafeRemove(ctx, obj)
+		return err
+	}
 	if err := d.WaitLimit(ctx); err != nil {
 		return err
 	}
-	return d.client.Delete(obj.GetID())
+	if err := d.client.Load().Delete(obj.GetID()); err != nil {
+		return err
+	}
+	if d.ConfirmDeletes {
+		return d.confirmDeleted(ctx, obj)
+	}
+	return nil
 }
 
-func (d *Pan115) Put(ctx context.Context, dstDir model.Obj, stream model.FileStreamer, up driver.UpdateProgress) (model.Obj, error) {
+func (d *Pan115) Put(ctx context.Context, dstDir model.Obj, stream model.FileStreamer, up driver.UpdateProgress) (obj model.Obj, err error) {
+	defer func() { err = d.maskErr(err) }()
+	if err := checkWritable(dstDir); err != nil {
+		return nil, err
+	}
+	ctx = withReqID(ctx)
 	if err := d.WaitLimit(ctx); err != nil {
 		return nil, err
 	}
@@ -159,93 +366,137 @@ func (d *Pan115) Put(ctx context.Context, dstDir model.Obj, stream model.FileStr
 		dirID    = dstDir.GetID()
 	)
 
-	if ok, err := d.client.UploadAvailable(); err != nil || !ok {
+	if d.CheckUploadDir {
+		if err := d.checkUploadDir(ctx, dirID); err != nil {
+			return nil, err
+		}
+	}
+	client := d.client.Load()
+	if ok, err := client.UploadAvailable(); err != nil || !ok {
 		return nil, err
 	}
-	if stream.GetSize() > d.client.UploadMetaInfo.SizeLimit {
+	if stream.GetSize() > client.UploadMetaInfo.SizeLimit {
 		return nil, driver115.ErrUploadTooLarge
 	}
 	//if digest, err = d.client.GetDigestResult(stream); err != nil {
 	//	return err
 	//}
 
-	const PreHashSize int64 = 128 * utils.KB
-	hashSize := PreHashSize
-	if stream.GetSize() < PreHashSize {
-		hashSize = stream.GetSize()
-	}
-	reader, err := stream.RangeRead(http_range.Range{Start: 0, Length: hashSize})
-	if err != nil {
-		return nil, err
-	}
-	preHash, err := utils.HashReader(utils.SHA1, reader)
+	preHash, fullHash, known, err := uploadHashes(ctx, stream)
 	if err != nil {
 		return nil, err
 	}
-	preHash = strings.ToUpper(preHash)
-	fullHash := stream.GetHash().GetHash(utils.SHA1)
-	if len(fullHash) <= 0 {
-		tmpF, err := stream.CacheFullInTempFile()
-		if err != nil {
+
+	for {
+		// rapid-upload
+		// note that 115 add timeout for rapid-upload,
+		// and "sig invalid" err is thrown even when the hash is correct after timeout.
+		if err = d.withSanitizedName(ctx, d.normName(stream.GetName()), func(name string) (err error) {
+			fastInfo, err = d.waitUploadInProgress(ctx, name, func() (*driver115.UploadInitResp, error) {
+				return d.retryRapidVerify(ctx, stream, func() (*driver115.UploadInitResp, error) {
+					return d.rapidUpload(ctx, stream.GetSize(), name, dirID, preHash, fullHash, stream)
+				})
+			})
+			return err
+		}); err != nil {
 			return nil, err
 		}
-		fullHash, err = utils.HashFile(utils.SHA1, tmpF)
-		if err != nil {
+		if matched, err := fastInfo.Ok(); err != nil {
 			return nil, err
+		} else if matched {
+			// a file that can't be looked up is left to the listing to find
+			f, _ := d.getNewFileByPickCode(ctx, fastInfo.PickCode)
+			if d.VerifyUploadVisible {
+				if err := d.confirmUploaded(ctx, dirID, stream, fullHash, f); err != nil {
+					return nil, err
+				}
+			}
+			if f == nil {
+				return nil, nil
+			}
+			return f, nil
 		}
-	}
-	fullHash = strings.ToUpper(fullHash)
-
-	// rapid-upload
-	// note that 115 add timeout for rapid-upload,
-	// and "sig invalid" err is thrown even when the hash is correct after timeout.
-	if fastInfo, err = d.rapidUpload(stream.GetSize(), stream.GetName(), dirID, preHash, fullHash, stream); err != nil {
-		return nil, err
-	}
-	if matched, err := fastInfo.Ok(); err != nil {
-		return nil, err
-	} else if matched {
-		f, err := d.getNewFileByPickCode(fastInfo.PickCode)
+		if !known {
+			break
+		}
+		// a known hash is trusted to find the file on 115, the file sent has to carry the hash of its content
+		known = false
+		actual, err := contentSHA1(stream)
 		if err != nil {
-			return nil, nil
+			return nil, err
+		}
+		if actual == fullHash {
+			break
 		}
-		return f, nil
+		logger(ctx).Warnf("the known sha1 %s of %s doesn't match its content %s, starting over", fullHash, stream.GetName(), actual)
+		if preHash, err = streamPreHash(stream); err != nil {
+			return nil, err
+		}
+		fullHash = actual
 	}
 
-	var uploadResult *UploadResult
 	// 闪传失败，上传
-	if stream.GetSize() <= 10*utils.MB { // 文件大小小于10MB，改用普通模式上传
-		if uploadResult, err = d.UploadByOSS(ctx, &fastInfo.UploadOSSParams, stream, dirID, up); err != nil {
+	logger(ctx).Debugf("rapid upload of %s missed, uploading by oss", stream.GetName())
+	return d.uploadMissed(ctx, &fastInfo.UploadOSSParams, stream, dstDir, fullHash, up)
+}
+
+// uploadMissed uploads stream by oss after rapid upload missed. With queue_upload_on_miss
+// the upload goes on in a task and it returns no file, like the put went through.
+func (d *Pan115) uploadMissed(ctx context.Context, params *driver115.UploadOSSParams, stream model.FileStreamer, dstDir model.Obj, fullHash string, up driver.UpdateProgress) (model.Obj, error) {
+	dirID := dstDir.GetID()
+	if d.QueueUploadOnMiss {
+		if _, err := d.queueUpload(ctx, params, stream, dstDir); err != nil {
 			return nil, err
 		}
-	} else {
-		// 分片上传
-		if uploadResult, err = d.UploadByMultipart(ctx, &fastInfo.UploadOSSParams, stream.GetSize(), stream, dirID, up); err != nil {
+		return nil, nil
+	}
+	uploadResult, err := d.uploadByOSS(ctx, params, stream, dirID, up)
+	if err != nil {
+		return nil, err
+	}
+	file, err := d.getNewFile(ctx, uploadResult.Data.FileID)
+	if err == nil && file.FileID == "" {
+		return nil, errors.Wrapf(ErrCallbackFailed, "%s not found after upload", stream.GetName())
+	}
+	if d.VerifyUploadVisible {
+		if err := d.confirmUploaded(ctx, dirID, stream, fullHash, file); err != nil {
 			return nil, err
 		}
 	}
-
-	file, err := d.getNewFile(uploadResult.Data.FileID)
-	if err != nil {
+	if file == nil {
 		return nil, nil
 	}
 	return file, nil
 }
 
 func (d *Pan115) OfflineList(ctx context.Context) ([]*driver115.OfflineTask, error) {
-	resp, err := d.client.ListOfflineTask(0)
+	resp, err := d.client.Load().ListOfflineTask(0)
 	if err != nil {
-		return nil, err
+		return nil, d.maskErr(err)
 	}
 	return resp.Tasks, nil
 }
 
 func (d *Pan115) OfflineDownload(ctx context.Context, uris []string, dstDir model.Obj) ([]string, error) {
-	return d.client.AddOfflineTaskURIs(uris, dstDir.GetID(), driver115.WithAppVer(appVer))
+	ctx = withReqID(ctx)
+	if err := d.requireCapability(ctx, "offline download", func(caps *Capabilities) bool { return caps.OfflineDownload }); err != nil {
+		return nil, err
+	}
+	hashes, err := d.client.Load().AddOfflineTaskURIs(uris, dstDir.GetID(), driver115.WithAppVer(d.appVersion()))
+	return hashes, d.maskErr(err)
 }
 
 func (d *Pan115) DeleteOfflineTasks(ctx context.Context, hashes []string, deleteFiles bool) error {
-	return d.client.DeleteOfflineTasks(hashes, deleteFiles)
+	ctx = withReqID(ctx)
+	_, err := d.inBatches(ctx, batchOffline, hashes, func(hashes []string) error {
+		form := url.Values{"hash": hashes, "flag": {"0"}}
+		if deleteFiles {
+			form.Set("flag", "1")
+		}
+		return d.postForm(ctx, driver115.ApiDelOfflineUrl, form)
+	})
+	return d.maskErr(err)
 }
 
 var _ driver.Driver = (*Pan115)(nil)
+var _ driver.Other = (*Pan115)(nil)
@@ -0,0 +1,194 @@
+package _115
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	driver115 "github.com/SheltonZhu/115driver/pkg/driver"
+	"github.com/alist-org/alist/v3/internal/conf"
+	"github.com/alist-org/alist/v3/internal/errs"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/stream"
+	"github.com/go-resty/resty/v2"
+)
+
+// rewriteTransport sends every request to the test server, whatever 115 host it was meant for
+type rewriteTransport struct {
+	target *url.URL
+}
+
+func (t *rewriteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	sent := req.Clone(req.Context())
+	if sent.Host == "" {
+		// redirects leave Host empty, the handlers still tell the hosts apart by it
+		sent.Host = req.URL.Host
+	}
+	sent.URL.Scheme = t.target.Scheme
+	sent.URL.Host = t.target.Host
+	resp, err := http.DefaultTransport.RoundTrip(sent)
+	if resp != nil {
+		// the response answers the request as it was made
+		resp.Request = req
+	}
+	return resp, err
+}
+
+func newTestDriver(t *testing.T, handler http.Handler) *Pan115 {
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	target, _ := url.Parse(srv.URL)
+	client := resty.New().SetTransport(&rewriteTransport{target: target})
+	d := &Pan115{}
+	d.client.Store(driver115.New(driver115.WithRestyClient(client)))
+	d.hookClient(d.client.Load())
+	d.initCaches()
+	return d
+}
+
+func writeJSON(w http.ResponseWriter, body string) {
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write([]byte(body))
+}
+
+// fakeTree serves 115 folder listings from memory, keyed by folder id
+type fakeTree map[string][]map[string]any
+
+func (tree fakeTree) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	cid := r.URL.Query().Get("cid")
+	files := tree[cid]
+	if files == nil {
+		files = []map[string]any{}
+	}
+	body, _ := json.Marshal(map[string]any{
+		"state":  true,
+		"cid":    cid,
+		"count":  len(files),
+		"offset": 0,
+		"data":   files,
+	})
+	writeJSON(w, string(body))
+}
+
+func fileInfo(id, cid, name string, size int64) map[string]any {
+	return map[string]any{"fid": id, "cid": cid, "n": name, "s": size, "pc": "pc" + id, "sha": "SHA" + id, "t": "2024-01-02 15:04"}
+}
+
+func testFileStream(t *testing.T, name string, content []byte) *stream.FileStream {
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, content, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = f.Close() })
+	return &stream.FileStream{Obj: &model.Object{Name: name, Size: int64(len(content))}, Reader: f}
+}
+
+func TestCheckUploadDirVirtualFolder(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/files/get_info", func(w http.ResponseWriter, r *http.Request) {
+		// a virtual folder has no backing cid, so 115 answers with an empty entry
+		writeJSON(w, `{"state":true,"data":[]}`)
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request to %s, upload should fail fast", r.URL.Path)
+	})
+	d := newTestDriver(t, mux)
+	d.CheckUploadDir = true
+
+	dir := &FileObj{File: driver115.File{FileID: "123", IsDirectory: true}}
+	_, err := d.Put(context.Background(), dir, testFileStream(t, "a.txt", []byte("a")), nil)
+	if !errors.Is(err, ErrUploadDirNotWritable) {
+		t.Errorf("expect %v, got %v", ErrUploadDirNotWritable, err)
+	}
+}
+
+func TestRebuildDuringList(t *testing.T) {
+	if conf.Conf == nil {
+		conf.Conf = conf.DefaultConfig()
+	}
+	tree := fakeTree{"0": {fileInfo("1", "0", "a.txt", 1), fileInfo("2", "0", "b.txt", 2)}}
+	d := newTestDriver(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/check/sso") {
+			writeJSON(w, `{"state":0,"data":{"user_id":1}}`)
+			return
+		}
+		tree.ServeHTTP(w, r)
+	}))
+	d.transport = d.client.Load().Client.GetClient().Transport
+	d.Cookie = fmt.Sprintf("UID=1_A1_%d;CID=c;SEID=s;KID=k", time.Now().Unix())
+	before := d.client.Load()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				files, err := d.getFiles(context.Background(), "0")
+				if err != nil {
+					t.Errorf("list during a rebuild: %v", err)
+					return
+				}
+				if len(files) != 2 {
+					t.Errorf("expect 2 files, got %d", len(files))
+					return
+				}
+			}
+		}()
+	}
+	for i := 0; i < 5; i++ {
+		if err := d.rebuildClient(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	wg.Wait()
+	if d.client.Load() == before {
+		t.Error("expect the rebuild to swap in a new client")
+	}
+}
+
+func TestMutate(t *testing.T) {
+	d := &Pan115{}
+	calls := 0
+	fn := func() error {
+		calls++
+		return nil
+	}
+	file := &FileObj{File: driver115.File{FileID: "1"}}
+	smart := &FileObj{File: driver115.File{FileID: smartFolderPrefix + "videos"}}
+	reader := &model.User{Role: model.GENERAL}
+	writer := &model.User{Role: model.GENERAL, Permission: 1 << 3}
+
+	for _, c := range []struct {
+		user *model.User
+		obj  model.Obj
+		err  error
+	}{
+		{nil, file, errs.PermissionDenied},
+		{reader, file, errs.PermissionDenied},
+		{writer, smart, errs.PermissionDenied},
+		{writer, file, nil},
+	} {
+		ctx := context.WithValue(context.Background(), "user", c.user)
+		if err := d.mutate(ctx, (*model.User).CanWrite, []model.Obj{c.obj}, fn); !errors.Is(err, c.err) {
+			t.Errorf("user %+v on %s: expect %v, got %v", c.user, c.obj.GetID(), c.err, err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("expect only the allowed call to run, got %d", calls)
+	}
+}
@@ -0,0 +1,229 @@
+package _115
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	driver115 "github.com/SheltonZhu/115driver/pkg/driver"
+	"github.com/alist-org/alist/v3/pkg/utils"
+	"github.com/go-resty/resty/v2"
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrUploadDirNotWritable = errors.New("upload destination does not exist or is not writable")
+	ErrNeedAcceptTerms      = errors.New("115 requires agreeing to its latest terms, please agree to them in the official 115 client and reload the storage")
+	ErrMaxDepthExceeded     = errors.New("max folder depth exceeded")
+	ErrFolderTooLarge       = errors.New("too many subfolders to compute the folder size")
+	ErrCyclicDir            = errors.New("folder cycle detected")
+	ErrRegionRestricted     = errors.New("this file is not available in your region")
+	ErrCallbackFailed       = errors.New("file uploaded to oss but 115 failed to register it")
+	ErrProtectedContent     = errors.New("this file is protected and can't be downloaded directly")
+	ErrDeleteNotConfirmed   = errors.New("115 reported the delete as done but the item still exists")
+	ErrNotRecoverable       = errors.New("deleted item not found in the recycle bin")
+	ErrUnderReview          = errors.New("this file is under content review and can't be downloaded until it passes")
+	ErrTranscoding          = errors.New("this video is still being transcoded, try again later")
+	ErrAuthExpired          = errors.New("115 login expired")
+	ErrFilenameRejected     = errors.New("115 rejected the file name")
+	ErrSessionRevoked       = errors.New("the 115 session of this storage was revoked, log in again and reload the storage")
+	ErrRateLimited          = errors.New("115 turned the request down for coming too often")
+	ErrRapidVerifyFailed    = errors.New("115 rejected the range hash of the rapid upload check")
+	ErrStaleCookie          = errors.New("the 115 cookie looks stale, export it again")
+	ErrRangeUnavailable     = errors.New("the file can't provide the range 115 asks to check")
+	ErrTooManyNewFolders    = errors.New("too many new folders for one path, check it for a typo")
+	ErrUploadInProgress     = errors.New("115 is receiving an upload of the same file from another session")
+	ErrUploadNotVisible     = errors.New("115 accepted the upload but the file doesn't show up in its folder as uploaded")
+	ErrSliderCaptcha        = errors.New("115 asks to slide a captcha for calling too often, complete the verification in the official 115 app")
+	ErrFeatureUnavailable   = errors.New("not available for this 115 account")
+	ErrLocalDirOutside      = errors.New("local_dir has to be a relative path inside local_download_dir")
+	ErrProtectedFolder      = errors.New("the folder is protected from deletes")
+)
+
+// codeErrs maps the response codes of an api that the 115driver library doesn't know about.
+// 115 publishes no list of its codes, each set holds the codes users reported from the apis
+// it is checked on, and is checked nowhere else.
+type codeErrs map[int]error
+
+// blockCodes come from the listing, download and upload init apis while 115 holds back
+// the whole account, 990009 until its new terms are agreed to and 911 until a slider captcha is solved
+var blockCodes = codeErrs{
+	990009: ErrNeedAcceptTerms,
+	911:    ErrSliderCaptcha,
+}
+
+// nameCodes come from files/add and files/batch_rename for a name with a word 115 censors
+var nameCodes = codeErrs{
+	20022: ErrFilenameRejected,
+}
+
+// downloadCodes come from the download url api for a file it won't hand out
+var downloadCodes = codeErrs{
+	50040: ErrRegionRestricted,
+	50041: ErrProtectedContent,
+	50042: ErrUnderReview,
+}
+
+// uploadInitCodes come from initupload.php, 990068 while another session uploads the same file
+var uploadInitCodes = codeErrs{
+	990068: ErrUploadInProgress,
+	20022:  ErrFilenameRejected,
+}
+
+// pauseErrs are errors that won't go away until the user acts,
+// calling the api again before that is pointless
+var pauseErrs = map[error]time.Duration{
+	ErrNeedAcceptTerms: 10 * time.Minute,
+	// reloading the storage with new credentials resets it
+	ErrSessionRevoked: 24 * time.Hour,
+	// CaptchaPause sets it per storage
+	ErrSliderCaptcha: 10 * time.Minute,
+}
+
+func isPauseErr(err error) bool {
+	for pauseErr := range pauseErrs {
+		if errors.Is(err, pauseErr) {
+			return true
+		}
+	}
+	return false
+}
+
+// noRetryErrs won't change on a second try
+var noRetryErrs = []error{
+	ErrRegionRestricted,
+	ErrProtectedContent,
+	ErrUnderReview,
+	ErrFilenameRejected,
+	ErrSliderCaptcha,
+}
+
+func isNoRetry(err error) bool {
+	for _, e := range noRetryErrs {
+		if errors.Is(err, e) {
+			return true
+		}
+	}
+	return false
+}
+
+type codeResp struct {
+	Errno driver115.StringInt `json:"errno"`
+	ErrNo driver115.StringInt `json:"errNo"`
+	Code  driver115.StringInt `json:"code"`
+	Error string              `json:"error"`
+	URL   string              `json:"url"`
+}
+
+// checkErrCode looks for the response codes of body in codes, body that isn't json is ignored
+func checkErrCode(body []byte, codes ...codeErrs) error {
+	var resp codeResp
+	if err := utils.Json.Unmarshal(body, &resp); err != nil {
+		return nil
+	}
+	for _, code := range []driver115.StringInt{resp.Errno, resp.ErrNo, resp.Code} {
+		if err, ok := lookupCode(int(code), codes); ok {
+			if err == ErrFilenameRejected {
+				return newFilenameRejected(resp.Error)
+			}
+			if err == ErrSliderCaptcha {
+				return newSliderCaptcha(resp)
+			}
+			if resp.Error != "" {
+				return errors.Wrap(err, resp.Error)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+func lookupCode(code int, codes []codeErrs) (error, bool) {
+	for _, c := range codes {
+		if err, ok := c[code]; ok {
+			return err, true
+		}
+	}
+	return nil, false
+}
+
+// checkCodes is checkErrCode for a response of an api call, the calls are paused when the error asks for it
+func (d *Pan115) checkCodes(ctx context.Context, body []byte, codes ...codeErrs) error {
+	err := checkErrCode(body, codes...)
+	if err != nil {
+		logger(ctx).Warnf("115 answered: %v", err)
+		d.pause.set(err)
+	}
+	return err
+}
+
+type pause struct {
+	mu    sync.Mutex
+	err   error
+	until time.Time
+	// durations overrides pauseErrs for this storage
+	durations map[error]time.Duration
+}
+
+func (p *pause) set(err error) {
+	for pauseErr, duration := range pauseErrs {
+		if errors.Is(err, pauseErr) {
+			p.mu.Lock()
+			if d, ok := p.durations[pauseErr]; ok {
+				duration = d
+			}
+			p.err, p.until = err, time.Now().Add(duration)
+			p.mu.Unlock()
+			return
+		}
+	}
+}
+
+// setDuration pauses calls for d after pauseErr, 0 not to pause
+func (p *pause) setDuration(pauseErr error, d time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.durations == nil {
+		p.durations = map[error]time.Duration{}
+	}
+	p.durations[pauseErr] = d
+}
+
+func (p *pause) check() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.err != nil && time.Now().Before(p.until) {
+		return p.err
+	}
+	p.err = nil
+	return nil
+}
+
+func (p *pause) reset() {
+	p.mu.Lock()
+	p.err = nil
+	p.mu.Unlock()
+}
+
+// hookClient makes every api response pass through checkLoginRedirect,
+// lets the throttle see every rate limited response and the watchdog every failed request
+func (d *Pan115) hookClient(client *driver115.Pan115Client) {
+	client.Client.SetRedirectPolicy(loginRedirectPolicy)
+	client.Client.OnAfterResponse(func(c *resty.Client, resp *resty.Response) error {
+		reqURL := d.masker.mask(resp.Request.URL)
+		if err := checkLoginRedirect(resp.RawResponse); err != nil {
+			logger(resp.Request.Context()).Warnf("%s %s: %v", resp.Request.Method, reqURL, err)
+			return err
+		}
+		if isRateLimited(resp.StatusCode()) {
+			d.throttle.limited(resp.Request.Context())
+			return errors.Wrapf(ErrRateLimited, "%s %s: %s", resp.Request.Method, reqURL, resp.Status())
+		}
+		d.watchdog.ok()
+		d.throttle.ok()
+		return nil
+	})
+	client.Client.OnError(func(req *resty.Request, err error) {
+		d.watchdog.fail(req.Context(), err, d.WatchdogThreshold, d.rebuildClient)
+	})
+}
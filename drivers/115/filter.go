@@ -0,0 +1,53 @@
+package _115
+
+import (
+	"context"
+	"strings"
+
+	"github.com/alist-org/alist/v3/pkg/utils"
+)
+
+// ListByExt lists the files directly under dirID with one of exts, folders are left out.
+// A single extension is filtered by 115 so only matching files are transferred, several
+// extensions, or one that 115 refuses to filter by, are filtered after a full listing.
+func (d *Pan115) ListByExt(ctx context.Context, dirID string, exts ...string) ([]FileObj, error) {
+	exts = utils.MustSliceConvert(exts, func(ext string) string {
+		return strings.ToLower(strings.TrimPrefix(ext, "."))
+	})
+	if err := d.WaitLimit(ctx); err != nil {
+		return nil, err
+	}
+	var (
+		files []FileObj
+		err   error
+	)
+	if len(exts) == 1 {
+		if files, err = d.listFiles(ctx, dirID, map[string]string{"suffix": exts[0]}); err != nil {
+			logger(ctx).Debugf("115 can't filter %s by %s, filtering locally: %v", dirID, exts[0], err)
+			if err = d.WaitLimit(ctx); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if files == nil {
+		if files, err = d.getFiles(ctx, dirID); err != nil {
+			return nil, err
+		}
+	}
+	return filterByExt(files, exts), nil
+}
+
+// filterByExt keeps the files with one of exts, also on a filtered listing
+// as 115 silently ignores filters it doesn't know
+func filterByExt(files []FileObj, exts []string) []FileObj {
+	if len(exts) == 0 {
+		return files
+	}
+	res := make([]FileObj, 0, len(files))
+	for _, f := range files {
+		if !f.IsDir() && utils.SliceContains(exts, utils.Ext(f.GetName())) {
+			res = append(res, f)
+		}
+	}
+	return res
+}
@@ -0,0 +1,101 @@
+package _115
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Xhofe/go-cache"
+	"github.com/pkg/errors"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	folderSizeTTL         = 10 * time.Minute
+	folderSizeConcurrency = 4
+	// folderSizeMaxDirs is how many folders one size may list, bigger trees get no size
+	folderSizeMaxDirs = 200
+	// folderSizeTooLarge is cached for a tree over folderSizeMaxDirs, so it isn't walked on every listing
+	folderSizeTooLarge = -1
+)
+
+var folderSizeExOpts = cache.WithEx[int64](folderSizeTTL)
+
+// ComputeFolderSize sums the size of every file under dirID, subfolders are listed
+// in parallel but still go through the rate limiter. It lists the whole subtree, so a
+// tree of more than folderSizeMaxDirs folders fails with ErrFolderTooLarge instead.
+// Either result is cached for folderSizeTTL.
+func (d *Pan115) ComputeFolderSize(ctx context.Context, dirID string) (int64, error) {
+	if size, ok := d.folderSizes.Get(dirID); ok {
+		if size == folderSizeTooLarge {
+			return 0, errors.Wrapf(ErrFolderTooLarge, "folder %s", dirID)
+		}
+		return size, nil
+	}
+	maxDepth := d.MaxDepth
+	if maxDepth <= 0 {
+		maxDepth = defaultMaxDepth
+	}
+	var (
+		mu      sync.Mutex
+		total   int64
+		listed  int
+		visited = map[string]struct{}{dirID: {}}
+	)
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(folderSizeConcurrency)
+	var sumDir func(dirID string, depth int) error
+	sumDir = func(dirID string, depth int) error {
+		if depth > maxDepth {
+			return errors.Wrapf(ErrMaxDepthExceeded, "folder %s (max %d)", dirID, maxDepth)
+		}
+		mu.Lock()
+		listed++
+		tooLarge := listed > folderSizeMaxDirs
+		mu.Unlock()
+		if tooLarge {
+			return errors.Wrapf(ErrFolderTooLarge, "over %d folders", folderSizeMaxDirs)
+		}
+		if err := d.WaitLimit(gctx); err != nil {
+			return err
+		}
+		files, err := d.getFiles(gctx, dirID)
+		if err != nil {
+			return err
+		}
+		var dirs []string
+		mu.Lock()
+		for _, f := range files {
+			if !f.IsDir() {
+				total += f.GetSize()
+				continue
+			}
+			if _, ok := visited[f.GetID()]; ok {
+				mu.Unlock()
+				return errors.Wrapf(ErrCyclicDir, "folder %s", f.GetID())
+			}
+			visited[f.GetID()] = struct{}{}
+			dirs = append(dirs, f.GetID())
+		}
+		mu.Unlock()
+		for _, id := range dirs {
+			id := id
+			// every worker may be waiting here, so fall back to walking the folder in place
+			if !g.TryGo(func() error { return sumDir(id, depth+1) }) {
+				if err := sumDir(id, depth+1); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	g.Go(func() error { return sumDir(dirID, 1) })
+	if err := g.Wait(); err != nil {
+		if errors.Is(err, ErrFolderTooLarge) {
+			d.folderSizes.Set(dirID, folderSizeTooLarge, folderSizeExOpts)
+		}
+		return 0, err
+	}
+	d.folderSizes.Set(dirID, total, folderSizeExOpts)
+	return total, nil
+}
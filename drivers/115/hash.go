@@ -0,0 +1,121 @@
+package _115
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/pkg/http_range"
+	"github.com/alist-org/alist/v3/pkg/utils"
+	"github.com/pkg/errors"
+)
+
+// preHashSize is how much of the start of a file the pre-hash of rapid upload covers
+const preHashSize int64 = 128 * utils.KB
+
+// KnownHash is what a caller knows of a file before uploading it, from an index for example
+type KnownHash struct {
+	SHA1 string
+	// PreHash is the SHA-1 of the first 128KB, read from the stream when it is empty
+	PreHash string
+	Size    int64
+}
+
+type knownHashKey struct{}
+
+// WithKnownHash makes the upload under ctx try rapid upload with h instead of hashing the stream.
+// h is checked against the content only when the rapid upload misses and the file has to be sent.
+func WithKnownHash(ctx context.Context, h KnownHash) context.Context {
+	return context.WithValue(ctx, knownHashKey{}, h)
+}
+
+// uploadHashes gets the pre-hash and SHA-1 to try rapid upload with, known tells they come
+// from the KnownHash of ctx, which has to be of a file the size of stream
+func uploadHashes(ctx context.Context, stream model.FileStreamer) (preHash, fullHash string, known bool, err error) {
+	h, ok := ctx.Value(knownHashKey{}).(KnownHash)
+	if !ok || h.SHA1 == "" {
+		if preHash, err = streamPreHash(stream); err != nil {
+			return "", "", false, err
+		}
+		fullHash = stream.GetHash().GetHash(utils.SHA1)
+		if fullHash == "" {
+			if fullHash, err = contentSHA1(stream); err != nil {
+				return "", "", false, err
+			}
+		}
+		return preHash, strings.ToUpper(fullHash), false, nil
+	}
+	if h.Size != stream.GetSize() {
+		return "", "", false, errors.Errorf("the known hash of %s is of %d bytes, the file has %d", stream.GetName(), h.Size, stream.GetSize())
+	}
+	preHash = h.PreHash
+	if h.Size <= preHashSize {
+		preHash = h.SHA1
+	}
+	if preHash == "" {
+		if preHash, err = streamPreHash(stream); err != nil {
+			return "", "", false, err
+		}
+	}
+	return strings.ToUpper(preHash), strings.ToUpper(h.SHA1), true, nil
+}
+
+// streamPreHash hashes the first preHashSize bytes of stream
+func streamPreHash(stream model.FileStreamer) (string, error) {
+	return rangeSHA1(stream, http_range.Range{Start: 0, Length: min(preHashSize, stream.GetSize())})
+}
+
+// signRange parses the range of a sign check, "start-end" with both ends included,
+// of a file of size bytes
+func signRange(spec string, size int64) (http_range.Range, error) {
+	var start, end int64
+	if _, err := fmt.Sscanf(spec, "%d-%d", &start, &end); err != nil {
+		return http_range.Range{}, errors.Wrapf(err, "sign check range %q", spec)
+	}
+	if start < 0 || end < start || end >= size {
+		return http_range.Range{}, errors.Errorf("sign check range %q is outside of the %d bytes of the file", spec, size)
+	}
+	return http_range.Range{Start: start, Length: end - start + 1}, nil
+}
+
+// rangeSHA1 hashes the bytes of stream in r. A stream cached in a temp file is read from it
+// at the offset of r, any other stream through RangeRead. A stream that can't provide the
+// whole range fails with ErrRangeUnavailable.
+func rangeSHA1(stream model.FileStreamer, r http_range.Range) (string, error) {
+	var reader io.Reader
+	if f := stream.GetFile(); f != nil {
+		reader = io.NewSectionReader(f, r.Start, r.Length)
+	} else {
+		rr, err := stream.RangeRead(r)
+		if err != nil {
+			return "", errors.Wrapf(ErrRangeUnavailable, "%d-%d of %s: %v", r.Start, r.Start+r.Length-1, stream.GetName(), err)
+		}
+		reader = rr
+	}
+	h := sha1.New()
+	n, err := io.Copy(h, reader)
+	if err == nil && n != r.Length {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return "", errors.Wrapf(ErrRangeUnavailable, "%d-%d of %s: %v", r.Start, r.Start+r.Length-1, stream.GetName(), err)
+	}
+	return strings.ToUpper(hex.EncodeToString(h.Sum(nil))), nil
+}
+
+// contentSHA1 hashes all of stream, caching it in a temp file
+func contentSHA1(stream model.FileStreamer) (string, error) {
+	tmpF, err := stream.CacheFullInTempFile()
+	if err != nil {
+		return "", err
+	}
+	hash, err := utils.HashFile(utils.SHA1, tmpF)
+	if err != nil {
+		return "", err
+	}
+	return strings.ToUpper(hash), nil
+}
@@ -0,0 +1,154 @@
+package _115
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/stream"
+	"github.com/alist-org/alist/v3/pkg/utils"
+	"github.com/pkg/errors"
+)
+
+type IngestResult struct {
+	Added   int      `json:"added"`
+	Skipped int      `json:"skipped"`
+	Failed  int      `json:"failed"`
+	Errors  []string `json:"errors,omitempty"`
+}
+
+type ingestTarget interface {
+	relocateTarget
+	Remove(ctx context.Context, obj model.Obj) error
+}
+
+// IngestLocalPath uploads the files under localPath on the server into dstDirID, keeping
+// the folder structure. A file whose name and SHA-1 match a file already there is skipped,
+// a changed one is uploaded and replaces the old version, so it can run on a schedule.
+func (d *Pan115) IngestLocalPath(ctx context.Context, localPath, dstDirID string) (*IngestResult, error) {
+	return ingest(withReqID(ctx), d, localPath, dstDirID)
+}
+
+func ingest(ctx context.Context, dst ingestTarget, localPath, dstDirID string) (*IngestResult, error) {
+	res := &IngestResult{}
+	dirs := map[string]model.Obj{".": &model.Object{ID: dstDirID, IsFolder: true}}
+	// listed caches the remote entries of every destination folder by name
+	listed := map[string]map[string]model.Obj{}
+	list := func(dir model.Obj) (map[string]model.Obj, error) {
+		if objs, ok := listed[dir.GetID()]; ok {
+			return objs, nil
+		}
+		objs, err := dst.List(ctx, dir, model.ListArgs{})
+		if err != nil {
+			return nil, err
+		}
+		byName := make(map[string]model.Obj, len(objs))
+		for _, obj := range objs {
+			byName[dst.normName(obj.GetName())] = obj
+		}
+		listed[dir.GetID()] = byName
+		return byName, nil
+	}
+	fail := func(rel string, err error) {
+		res.Failed++
+		res.Errors = append(res.Errors, rel+": "+err.Error())
+		logger(ctx).Warnf("ingest %s failed: %v", rel, err)
+	}
+
+	err := filepath.WalkDir(localPath, func(path string, entry fs.DirEntry, err error) error {
+		rel, _ := filepath.Rel(localPath, path)
+		if err != nil {
+			if rel == "." {
+				return err
+			}
+			fail(rel, err)
+			if entry != nil && entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if rel == "." || !entry.IsDir() && !entry.Type().IsRegular() {
+			return nil
+		}
+		parent := dirs[filepath.Dir(rel)]
+		existing, err := list(parent)
+		if err != nil {
+			return errors.Wrapf(err, "list folder of %s", rel)
+		}
+		old := existing[dst.normName(entry.Name())]
+		if entry.IsDir() {
+			if old == nil || !old.IsDir() {
+				if old, err = relocateDir(ctx, dst, parent, entry.Name()); err != nil {
+					return errors.Wrapf(err, "create folder %s", rel)
+				}
+			}
+			dirs[rel] = old
+			return nil
+		}
+		if old != nil && old.IsDir() {
+			old = nil
+		}
+		added, err := ingestFile(ctx, dst, parent, path, old)
+		switch {
+		case err != nil:
+			fail(rel, err)
+			if isPauseErr(err) {
+				return err
+			}
+		case added:
+			res.Added++
+		default:
+			res.Skipped++
+		}
+		return nil
+	})
+	logger(ctx).Infof("ingested %s: %d added, %d skipped, %d failed", localPath, res.Added, res.Skipped, res.Failed)
+	return res, err
+}
+
+// ingestFile uploads the local file at path into dir unless old has the same content,
+// old is removed once the new version is up
+func ingestFile(ctx context.Context, dst ingestTarget, dir model.Obj, path string, old model.Obj) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return false, err
+	}
+	sha1, err := utils.HashFile(utils.SHA1, f)
+	if err != nil {
+		return false, err
+	}
+	if old != nil && strings.EqualFold(old.GetHash().GetHash(utils.SHA1), sha1) {
+		return false, nil
+	}
+	s := &stream.FileStream{
+		Ctx: ctx,
+		Obj: &model.Object{
+			Name:     info.Name(),
+			Size:     info.Size(),
+			Modified: info.ModTime(),
+			HashInfo: utils.NewHashInfo(utils.SHA1, sha1),
+		},
+		Reader:   f,
+		Mimetype: utils.GetMimeType(info.Name()),
+	}
+	if _, err := dst.Put(ctx, dir, s, func(float64) {}); err != nil {
+		return false, err
+	}
+	if old != nil {
+		if err := dst.Remove(ctx, old); err != nil {
+			return true, errors.Wrap(err, "uploaded but failed to remove the old version")
+		}
+	}
+	return true, nil
+}
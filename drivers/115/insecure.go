@@ -0,0 +1,39 @@
+package _115
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/pkg/errors"
+)
+
+// what HTTPDownloads does with a download url that isn't https
+const (
+	httpAllow   = "allow-http"
+	httpUpgrade = "upgrade-to-https"
+	httpReject  = "reject"
+)
+
+var ErrInsecureURL = errors.New("115 handed out a download url without https")
+
+// checkDownloadScheme applies HTTPDownloads to a plain http link of name: warned about and kept,
+// switched to https, or refused with ErrInsecureURL. The signed url is never logged, only its host.
+func (d *Pan115) checkDownloadScheme(ctx context.Context, name string, link *model.Link) error {
+	u, err := url.Parse(link.URL)
+	if err != nil || u.Scheme != "http" {
+		return nil
+	}
+	switch d.HTTPDownloads {
+	case httpReject:
+		logger(ctx).Warnf("refusing the plain http download url of %s from %s", name, u.Host)
+		return errors.Wrap(ErrInsecureURL, u.Host)
+	case httpUpgrade:
+		u.Scheme = "https"
+		link.URL = u.String()
+		logger(ctx).Debugf("upgraded the download url of %s from %s to https", name, u.Host)
+	default:
+		logger(ctx).Warnf("115 handed out a plain http download url of %s from %s, set http_downloads to upgrade or reject these", name, u.Host)
+	}
+	return nil
+}
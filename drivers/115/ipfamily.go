@@ -0,0 +1,46 @@
+package _115
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+)
+
+const (
+	ipFamilyAuto = "auto"
+	ipFamilyV4   = "ipv4"
+	ipFamilyV6   = "ipv6"
+)
+
+// familyNetwork narrows network to the ip family, auto and networks that aren't tcp are kept
+func familyNetwork(family, network string) string {
+	if network != "tcp" && network != "tcp4" && network != "tcp6" {
+		return network
+	}
+	switch family {
+	case ipFamilyV4:
+		return "tcp4"
+	case ipFamilyV6:
+		return "tcp6"
+	}
+	return network
+}
+
+// applyIPFamily makes client connect over family only. Uploads to oss share the http client
+// of the api, so this covers both. Auto leaves the dialer of the system alone.
+func applyIPFamily(client *resty.Client, family string) error {
+	if family == "" || family == ipFamilyAuto {
+		return nil
+	}
+	transport, err := client.Transport()
+	if err != nil {
+		return err
+	}
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return dialer.DialContext(ctx, familyNetwork(family, network), addr)
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+package _115
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/errs"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/task"
+	"github.com/pkg/errors"
+	"github.com/xhofe/tache"
+)
+
+// LocalDownloadTask downloads files of a 115 storage into a folder on the server
+type LocalDownloadTask struct {
+	task.TaskExtension
+	Status    string   `json:"-"`
+	StorageMp string   `json:"storage_mp"`
+	FileIDs   []string `json:"file_ids"`
+	// LocalDir is resolved under local_download_dir already
+	LocalDir string `json:"local_dir"`
+	d        *Pan115
+}
+
+var (
+	localDownloadsOnce sync.Once
+	localDownloads     *tache.Manager[*LocalDownloadTask]
+)
+
+// localDownloadManager runs the downloads of batch_download_to_local. It belongs to the driver,
+// the tasks keep a pointer to their storage, so they aren't persisted and run one at a time.
+func localDownloadManager() *tache.Manager[*LocalDownloadTask] {
+	localDownloadsOnce.Do(func() {
+		localDownloads = tache.NewManager[*LocalDownloadTask](tache.WithWorks(1), tache.WithMaxRetry(2))
+	})
+	return localDownloads
+}
+
+type QueuedTask struct {
+	TaskID string `json:"task_id"`
+	Name   string `json:"name"`
+}
+
+func (t *LocalDownloadTask) GetName() string {
+	return fmt.Sprintf("download %d items of [%s] to %s", len(t.FileIDs), t.StorageMp, t.LocalDir)
+}
+
+func (t *LocalDownloadTask) GetStatus() string {
+	return t.Status
+}
+
+func (t *LocalDownloadTask) Run() error {
+	t.ReinitCtx()
+	t.ClearEndTime()
+	t.SetStartTime(time.Now())
+	defer func() { t.SetEndTime(time.Now()) }()
+	t.Status = "downloading"
+	// the progress of the batch is the mean of the progress of its files
+	var mu sync.Mutex
+	files := map[string]float64{}
+	res, err := t.d.BatchDownloadToLocal(t.Ctx(), t.FileIDs, t.LocalDir, func(path string, percentage float64) {
+		mu.Lock()
+		defer mu.Unlock()
+		files[path] = percentage
+		var sum float64
+		for _, p := range files {
+			sum += p
+		}
+		t.SetProgress(sum / float64(len(files)))
+	})
+	if err != nil {
+		return err
+	}
+	t.Status = fmt.Sprintf("downloaded %d files, %d failed", res.Downloaded, res.Failed)
+	if res.Failed > 0 {
+		for _, f := range res.Files {
+			if f.Error != "" {
+				return errors.Errorf("%d of %d files failed, %s: %s", res.Failed, len(res.Files), f.Path, f.Error)
+			}
+		}
+	}
+	return nil
+}
+
+// localDownloadDir resolves dir under local_download_dir, the only folder of the server
+// downloads may write to. An unset local_download_dir turns local downloads off.
+func (d *Pan115) localDownloadDir(dir string) (string, error) {
+	if d.LocalDownloadDir == "" {
+		return "", errors.Wrap(errs.NotSupport, "local_download_dir of the storage isn't set")
+	}
+	dir = filepath.FromSlash(dir)
+	if dir == "" {
+		dir = "."
+	}
+	if !filepath.IsLocal(dir) {
+		return "", errors.Wrapf(ErrLocalDirOutside, "%s", dir)
+	}
+	base, err := filepath.Abs(d.LocalDownloadDir)
+	if err != nil {
+		return "", err
+	}
+	target := filepath.Join(base, dir)
+	// a symlink inside the base can still point out of it, the part of target that
+	// exists already has to stay in the base once its links are followed
+	realBase, err := filepath.EvalSymlinks(base)
+	if err != nil {
+		return "", err
+	}
+	existing := target
+	for {
+		real, err := filepath.EvalSymlinks(existing)
+		if err == nil {
+			if rel, err := filepath.Rel(realBase, real); err != nil || !filepath.IsLocal(rel) {
+				return "", errors.Wrapf(ErrLocalDirOutside, "%s", dir)
+			}
+			return target, nil
+		}
+		if !os.IsNotExist(err) || existing == base {
+			return "", err
+		}
+		existing = filepath.Dir(existing)
+	}
+}
+
+// QueueLocalDownload adds a task downloading fileIDs into dir under local_download_dir
+func (d *Pan115) QueueLocalDownload(ctx context.Context, fileIDs []string, dir string) (*QueuedTask, error) {
+	localDir, err := d.localDownloadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	taskCreator, _ := ctx.Value("user").(*model.User)
+	t := &LocalDownloadTask{
+		TaskExtension: task.TaskExtension{
+			Creator: taskCreator,
+		},
+		Status:    "queued",
+		StorageMp: d.MountPath,
+		FileIDs:   fileIDs,
+		LocalDir:  localDir,
+		d:         d,
+	}
+	localDownloadManager().Add(t)
+	logger(ctx).Infof("queued the download of %s to %s as task %s", strings.Join(fileIDs, ","), localDir, t.GetID())
+	return &QueuedTask{TaskID: t.GetID(), Name: t.GetName()}, nil
+}
+
+// LocalDownloadTasks lists the local download tasks of the storage
+func (d *Pan115) LocalDownloadTasks() []TaskInfo {
+	return taskInfos(localDownloadManager().GetByCondition(func(t *LocalDownloadTask) bool { return t.d == d }))
+}
+
+// cancelLocalDownloads stops the local download tasks of the storage when it's dropped
+func (d *Pan115) cancelLocalDownloads() {
+	localDownloadManager().CancelByCondition(func(t *LocalDownloadTask) bool { return t.d == d })
+}
@@ -0,0 +1,118 @@
+package _115
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/alist-org/alist/v3/pkg/utils"
+	log "github.com/sirupsen/logrus"
+)
+
+const masked = "***"
+
+// minSecretLen keeps short values, which would mask unrelated parts of the text, out of a masker
+const minSecretLen = 6
+
+// credentialParamRe matches the values of query and form parameters that carry
+// signatures and tokens, as well as a cookie header copied into a message
+var credentialParamRe = regexp.MustCompile(
+	`(?i)((?:^|[?&\s"'])(?:sig|sign|sign_key|sign_val|k_ec|token|signature|security-token|x-oss-security-token|ossaccesskeyid|accesskeyid)=)[^&\s"']+` +
+		`|(cookie:\s*)[^\r\n"]+`)
+
+// masker replaces the credentials of a storage in text: the values of its cookie,
+// QR code token and oss token, and the signatures in urls
+type masker struct {
+	mu      sync.RWMutex
+	secrets []string
+}
+
+// add remembers secrets, empty and short values are left out
+func (m *masker) add(secrets ...string) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, s := range secrets {
+		if len(s) >= minSecretLen && !utils.SliceContains(m.secrets, s) {
+			m.secrets = append(m.secrets, s)
+		}
+	}
+}
+
+func (m *masker) mask(s string) string {
+	if m == nil {
+		return s
+	}
+	s = credentialParamRe.ReplaceAllString(s, "${1}${2}"+masked)
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, secret := range m.secrets {
+		s = strings.ReplaceAll(s, secret, masked)
+	}
+	return s
+}
+
+// maskedError reads as err with its credentials masked, errors.Is and errors.As still see err
+type maskedError struct {
+	err error
+	msg string
+}
+
+func (e *maskedError) Error() string { return e.msg }
+
+func (e *maskedError) Unwrap() error { return e.err }
+
+// maskErr masks the credentials of the storage in the message of err,
+// an error that has none is returned as is
+func (d *Pan115) maskErr(err error) error {
+	if err == nil || d.masker == nil {
+		return err
+	}
+	if msg := d.masker.mask(err.Error()); msg != err.Error() {
+		return &maskedError{err: err, msg: msg}
+	}
+	return err
+}
+
+// maskHook masks the messages logged by the driver with the maskers of every storage
+// that has MaskCredentials on, logger has no way to tell which storage logs
+type maskHook struct {
+	mu      sync.RWMutex
+	maskers map[*masker]struct{}
+}
+
+var (
+	maskers     = &maskHook{maskers: map[*masker]struct{}{}}
+	maskHookAdd sync.Once
+)
+
+func (h *maskHook) register(m *masker) {
+	maskHookAdd.Do(func() { log.AddHook(h) })
+	h.mu.Lock()
+	h.maskers[m] = struct{}{}
+	h.mu.Unlock()
+}
+
+func (h *maskHook) unregister(m *masker) {
+	h.mu.Lock()
+	delete(h.maskers, m)
+	h.mu.Unlock()
+}
+
+func (h *maskHook) Levels() []log.Level {
+	return log.AllLevels
+}
+
+func (h *maskHook) Fire(entry *log.Entry) error {
+	if entry.Data["driver"] != "115" {
+		return nil
+	}
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for m := range h.maskers {
+		entry.Message = m.mask(entry.Message)
+	}
+	return nil
+}
@@ -6,11 +6,55 @@ import (
 )
 
 type Addition struct {
-	Cookie       string  `json:"cookie" type:"text" help:"one of QR code token and cookie required"`
-	QRCodeToken  string  `json:"qrcode_token" type:"text" help:"one of QR code token and cookie required"`
-	QRCodeSource string  `json:"qrcode_source" type:"select" options:"web,android,ios,tv,alipaymini,wechatmini,qandroid" default:"linux" help:"select the QR code device, default linux"`
-	PageSize     int64   `json:"page_size" type:"number" default:"1000" help:"list api per page size of 115 driver"`
-	LimitRate    float64 `json:"limit_rate" type:"float" default:"2" help:"limit all api request rate ([limit]r/1s)"`
+	Cookie              string  `json:"cookie" type:"text" help:"one of QR code token and cookie required"`
+	QRCodeToken         string  `json:"qrcode_token" type:"text" help:"one of QR code token and cookie required"`
+	QRCodeSource        string  `json:"qrcode_source" type:"select" options:"web,android,ios,tv,alipaymini,wechatmini,qandroid" default:"linux" help:"select the QR code device, default linux"`
+	PageSize            int64   `json:"page_size" type:"number" default:"1000" help:"list api per page size of 115 driver"`
+	LimitRate           float64 `json:"limit_rate" type:"float" default:"2" help:"limit all api request rate ([limit]r/1s)"`
+	CheckUploadDir      bool    `json:"check_upload_dir" type:"bool" default:"false" help:"check the upload folder is writable before hashing"`
+	MaxDepth            int     `json:"max_depth" type:"number" default:"64" help:"max folder depth of recursive operations"`
+	QueueUploadOnMiss   bool    `json:"queue_upload_on_miss" type:"bool" default:"false" help:"upload in background when rapid upload misses, see upload_tasks"`
+	PreferredQuality    string  `json:"preferred_quality" type:"select" options:"auto,1080p,720p,480p,360p" default:"auto" help:"video preview quality, auto previews the original"`
+	ShowFolderSize      bool    `json:"show_folder_size" type:"bool" default:"false" help:"compute folder sizes, skipped above 200 subfolders"`
+	PlayProtected       bool    `json:"play_protected" type:"bool" default:"false" help:"preview protected videos by play url"`
+	ConfirmDeletes      bool    `json:"confirm_deletes" type:"bool" default:"false" help:"fail a delete if the item still exists after it"`
+	SafeDelete          bool    `json:"safe_delete" type:"bool" default:"false" help:"make sure deleted items are in the recycle bin"`
+	DownloadReferer     string  `json:"download_referer" type:"text" help:"referer sent to get and download links"`
+	DisableCache        bool    `json:"disable_cache" type:"bool" default:"false" help:"disable all caches of this storage"`
+	NormalizeNames      bool    `json:"normalize_names" type:"bool" default:"false" help:"normalize names to unicode NFC"`
+	UploadThreads       int     `json:"upload_threads" type:"number" default:"1" help:"multipart upload threads, more than 1 disables sequential mode"`
+	UploadPartSize      int64   `json:"upload_part_size" type:"number" default:"0" help:"multipart upload part size in MB, 0 for auto"`
+	ReuseOSSToken       bool    `json:"reuse_oss_token" type:"bool" default:"false" help:"share the oss token between uploads"`
+	SanitizeNames       bool    `json:"sanitize_names" type:"bool" default:"false" help:"mask sensitive words 115 rejects in names and retry"`
+	ReloginOnExpire     bool    `json:"relogin_on_expire" type:"bool" default:"true" help:"log in again and retry once when the login expires"`
+	WatchdogThreshold   int     `json:"watchdog_threshold" type:"number" default:"0" help:"rebuild the client after this many failed requests in a row, 0 to disable"`
+	MinLimitRate        float64 `json:"min_limit_rate" type:"float" default:"0.5" help:"lowest rate of adaptive throttling"`
+	MaxLimitRate        float64 `json:"max_limit_rate" type:"float" default:"0" help:"highest rate of adaptive throttling, 0 to disable"`
+	RepairParts         bool    `json:"repair_parts" type:"bool" default:"true" help:"upload mismatched parts again when oss refuses to complete"`
+	RetryRapidVerify    bool    `json:"retry_rapid_verify" type:"bool" default:"true" help:"retry rapid upload once when its range hash is rejected"`
+	PlaybackChunkSize   int64   `json:"playback_chunk_size" type:"number" default:"0" help:"proxy chunk size in MB for open-ended ranges, 0 to disable"`
+	StrictCookie        bool    `json:"strict_cookie" type:"bool" default:"false" help:"refuse stale cookies instead of warning"`
+	OrderBy             string  `json:"order_by" type:"select" options:"file_name,file_size,user_ptime,user_utime,file_type" default:"user_ptime" help:"default sort of listings"`
+	OrderDirection      string  `json:"order_direction" type:"select" options:"asc,desc" default:"asc"`
+	FolderSort          bool    `json:"folder_sort" type:"bool" default:"false" help:"use the sort set for a folder in the 115 client"`
+	MaskCredentials     bool    `json:"mask_credentials" type:"bool" default:"true" help:"mask credentials in errors and logs"`
+	ResolveRedirects    bool    `json:"resolve_redirects" type:"bool" default:"false" help:"follow download url redirects to the cdn url"`
+	MaxNewFolders       int     `json:"max_new_folders" type:"number" default:"10" help:"max folders created for one path, 0 for no limit"`
+	StrictListing       bool    `json:"strict_listing" type:"bool" default:"false" help:"fail listings with malformed entries instead of skipping them"`
+	IPFamily            string  `json:"ip_family" type:"select" options:"auto,ipv4,ipv6" default:"auto" help:"ip family used to connect to 115"`
+	CaptchaPause        int     `json:"captcha_pause" type:"number" default:"10" help:"minutes to pause after a slider captcha, 0 to disable"`
+	VerifyUploadVisible bool    `json:"verify_upload_visible" type:"bool" default:"false" help:"check uploaded files show up in their folder"`
+	AppVersionOverride  string  `json:"app_version_override" type:"text" help:"115 client version used in signatures, empty to fetch it"`
+	BatchSizes          string  `json:"batch_sizes" type:"text" help:"items per call, like delete=200,star=500"`
+	BatchConcurrency    int     `json:"batch_concurrency" type:"number" default:"1" help:"calls of a batch sent at once"`
+	DuplicateUpload     string  `json:"duplicate_upload" type:"select" options:"wait,fail" default:"wait" help:"wait for or fail an upload another session is sending"`
+	DuplicateUploadWait int     `json:"duplicate_upload_wait" type:"number" default:"30" help:"seconds between checks while waiting"`
+	HTTPDownloads       string  `json:"http_downloads" type:"select" options:"allow-http,upgrade-to-https,reject" default:"allow-http" help:"handling of download urls without https"`
+	SmartFolders        string  `json:"smart_folders" type:"text" help:"read-only root folders, a json array of {name,type,starred,modified,recursive}"`
+	APIDomain           string  `json:"api_domain" type:"text" default:"115.com" help:"domain of the 115 apis"`
+	CheckCapabilities   bool    `json:"check_capabilities" type:"bool" default:"false" help:"check vip and offline quota at init"`
+	LocalDownloadDir    string  `json:"local_download_dir" type:"text" help:"server folder of batch_download_to_local, empty to disable"`
+	ProtectedFolders    string  `json:"protected_folders" type:"text" help:"comma separated ids of folders that can't be deleted"`
 	driver.RootID
 }
 
@@ -0,0 +1,54 @@
+package _115
+
+import (
+	"context"
+	stdpath "path"
+	"strings"
+
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/pkg/errors"
+)
+
+// MakeDirAll makes sure every folder of dirPath exists under parentID and returns the last one,
+// folders already there are reused. A path that needs more than MaxNewFolders new folders,
+// likely a typo, fails with ErrTooManyNewFolders before any of them is created.
+func (d *Pan115) MakeDirAll(ctx context.Context, parentID, dirPath string) (model.Obj, error) {
+	var names []string
+	for _, name := range strings.Split(stdpath.Clean("/"+dirPath), "/") {
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	var parent model.Obj = &model.Object{ID: parentID, IsFolder: true}
+	for i, name := range names {
+		if err := d.WaitLimit(ctx); err != nil {
+			return nil, err
+		}
+		files, err := d.getFiles(ctx, parent.GetID())
+		if err != nil {
+			return nil, errors.Wrapf(err, "list %s", stdpath.Join(names[:i]...))
+		}
+		var found model.Obj
+		for j := range files {
+			if files[j].IsDir() && d.normName(files[j].GetName()) == d.normName(name) {
+				found = &files[j]
+				break
+			}
+		}
+		if found != nil {
+			parent = found
+			continue
+		}
+		if missing := len(names) - i; d.MaxNewFolders > 0 && missing > d.MaxNewFolders {
+			return nil, errors.Wrapf(ErrTooManyNewFolders, "%s needs %d new folders, at most %d are allowed",
+				dirPath, missing, d.MaxNewFolders)
+		}
+		for _, name := range names[i:] {
+			if parent, err = relocateDir(ctx, d, parent, name); err != nil {
+				return nil, errors.Wrapf(err, "create folder %s", name)
+			}
+		}
+		break
+	}
+	return parent, nil
+}
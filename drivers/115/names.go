@@ -0,0 +1,67 @@
+package _115
+
+import (
+	"context"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/text/unicode/norm"
+)
+
+// maxSanitizeRetries is how many rejected terms of one name get masked before giving up
+const maxSanitizeRetries = 3
+
+// normName is the form of name that gets uploaded and compared with existing names.
+// macOS hands out NFD names, with NormalizeNames on they become NFC so the same name
+// typed on different systems doesn't end up as two look-alike entries.
+func (d *Pan115) normName(name string) string {
+	if d.NormalizeNames {
+		return norm.NFC.String(name)
+	}
+	return name
+}
+
+// FilenameRejectedError is ErrFilenameRejected with the term 115 objected to, when it said
+type FilenameRejectedError struct {
+	Term string
+	Msg  string
+}
+
+func (e *FilenameRejectedError) Error() string {
+	if e.Msg == "" {
+		return ErrFilenameRejected.Error()
+	}
+	return e.Msg + ": " + ErrFilenameRejected.Error()
+}
+
+func (e *FilenameRejectedError) Is(target error) bool {
+	return target == ErrFilenameRejected
+}
+
+// newFilenameRejected reads the term out of messages like "文件名含有敏感词：xxx"
+func newFilenameRejected(msg string) error {
+	e := &FilenameRejectedError{Msg: msg}
+	for _, sep := range []string{"：", ":"} {
+		if _, term, ok := strings.Cut(msg, sep); ok {
+			e.Term = strings.Trim(strings.TrimSpace(term), `"'“”「」`)
+			break
+		}
+	}
+	return e
+}
+
+// withSanitizedName calls fn with name. When 115 rejects the name and SanitizeNames
+// is on, fn is called again with the rejected term masked.
+func (d *Pan115) withSanitizedName(ctx context.Context, name string, fn func(name string) error) error {
+	for i := 0; ; i++ {
+		err := fn(name)
+		var rejected *FilenameRejectedError
+		if !d.SanitizeNames || i >= maxSanitizeRetries || !errors.As(err, &rejected) ||
+			rejected.Term == "" || !strings.Contains(name, rejected.Term) {
+			return err
+		}
+		masked := strings.ReplaceAll(name, rejected.Term, strings.Repeat("_", len([]rune(rejected.Term))))
+		logger(ctx).Warnf("115 rejected the name %s for %q, retrying as %s", name, rejected.Term, masked)
+		name = masked
+	}
+}
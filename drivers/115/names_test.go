@@ -0,0 +1,48 @@
+package _115
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+const (
+	nfcName = "caf\u00e9.txt"
+	nfdName = "cafe\u0301.txt"
+)
+
+func TestNormName(t *testing.T) {
+	d := &Pan115{}
+	if d.normName(nfdName) != nfdName {
+		t.Error("names should be left as they are by default")
+	}
+	d.NormalizeNames = true
+	if d.normName(nfdName) != nfcName || d.normName(nfcName) != nfcName {
+		t.Errorf("expect both forms normalized to %q", nfcName)
+	}
+}
+
+func TestSanitizedName(t *testing.T) {
+	var names []string
+	rename := func(name string) error {
+		names = append(names, name)
+		if strings.Contains(name, "bad") {
+			return newFilenameRejected("文件名含有敏感词：bad")
+		}
+		return nil
+	}
+	d := &Pan115{}
+	if err := d.withSanitizedName(context.Background(), "a bad badge", rename); !errors.Is(err, ErrFilenameRejected) || len(names) != 1 {
+		t.Errorf("expect the rejection without retrying by default, got %v after %d tries", err, len(names))
+	}
+
+	d.SanitizeNames = true
+	names = nil
+	if err := d.withSanitizedName(context.Background(), "a bad badge", rename); err != nil {
+		t.Fatalf("expect the sanitized name to be accepted, got %v", err)
+	}
+	if len(names) != 2 || names[1] != "a ___ ___ge" {
+		t.Errorf("expect one retry with the term masked, got %v", names)
+	}
+}
@@ -0,0 +1,85 @@
+package _115
+
+import (
+	"context"
+
+	driver115 "github.com/SheltonZhu/115driver/pkg/driver"
+	"github.com/pkg/errors"
+)
+
+type OfflineTaskResult struct {
+	TaskID string `json:"task_id"`
+	Name   string `json:"name"`
+	// Completed is set when the task had finished before it was canceled or deleted
+	Completed    bool `json:"completed"`
+	Canceled     bool `json:"canceled"`
+	FilesDeleted bool `json:"files_deleted"`
+}
+
+// findOfflineTask looks taskID, the info hash of the task, up in every page of the offline task list
+func (d *Pan115) findOfflineTask(ctx context.Context, taskID string) (*driver115.OfflineTask, error) {
+	for page := int64(1); ; page++ {
+		if err := d.WaitLimit(ctx); err != nil {
+			return nil, err
+		}
+		resp, err := d.client.Load().ListOfflineTask(page)
+		if err != nil {
+			return nil, err
+		}
+		for _, task := range resp.Tasks {
+			if task.InfoHash == taskID {
+				return task, nil
+			}
+		}
+		if len(resp.Tasks) == 0 || page >= resp.PageCount {
+			return nil, errors.Wrapf(driver115.ErrNotExist, "offline task %s", taskID)
+		}
+	}
+}
+
+// CancelOfflineTask stops taskID and drops it from the task list, what it downloaded so far is kept.
+// A task that has completed already is left alone, there is nothing to stop.
+func (d *Pan115) CancelOfflineTask(ctx context.Context, taskID string) (*OfflineTaskResult, error) {
+	task, err := d.findOfflineTask(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+	res := &OfflineTaskResult{TaskID: taskID, Name: task.Name, Completed: task.IsDone()}
+	if res.Completed {
+		logger(ctx).Infof("offline task %s (%s) has completed already, not canceling it", task.Name, taskID)
+		return res, nil
+	}
+	if err := d.deleteOfflineTask(ctx, taskID, false); err != nil {
+		return nil, err
+	}
+	res.Canceled = true
+	logger(ctx).Infof("canceled offline task %s (%s) at %.1f%%", task.Name, taskID, task.Percent)
+	return res, nil
+}
+
+// DeleteOfflineTask removes taskID from the task list whatever its state, stopping it if it
+// still runs, and with deleteFiles also deletes the files it downloaded
+func (d *Pan115) DeleteOfflineTask(ctx context.Context, taskID string, deleteFiles bool) (*OfflineTaskResult, error) {
+	task, err := d.findOfflineTask(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+	if err := d.deleteOfflineTask(ctx, taskID, deleteFiles); err != nil {
+		return nil, err
+	}
+	logger(ctx).Infof("deleted offline task %s (%s), files deleted: %v", task.Name, taskID, deleteFiles)
+	return &OfflineTaskResult{
+		TaskID:       taskID,
+		Name:         task.Name,
+		Completed:    task.IsDone(),
+		Canceled:     !task.IsDone() && !task.IsFailed(),
+		FilesDeleted: deleteFiles,
+	}, nil
+}
+
+func (d *Pan115) deleteOfflineTask(ctx context.Context, taskID string, deleteFiles bool) error {
+	if err := d.WaitLimit(ctx); err != nil {
+		return err
+	}
+	return d.client.Load().DeleteOfflineTasks([]string{taskID}, deleteFiles)
+}
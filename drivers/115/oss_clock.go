@@ -0,0 +1,58 @@
+package _115
+
+import (
+	"context"
+	"encoding/xml"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/aliyun/aliyun-oss-go-sdk/oss"
+	"github.com/pkg/errors"
+)
+
+// ossClock is the offset of oss time from the local clock, learned from RequestTimeTooSkewed
+// errors. oss signs the Date header so requests are dated by it once the clocks disagree.
+type ossClock struct {
+	offset atomic.Int64
+}
+
+func (c *ossClock) options() []oss.Option {
+	offset := time.Duration(c.offset.Load())
+	if offset == 0 {
+		return nil
+	}
+	return []oss.Option{oss.SetHeader(oss.HTTPHeaderDate, time.Now().Add(offset).UTC().Format(http.TimeFormat))}
+}
+
+// sync takes the offset from a RequestTimeTooSkewed error, it reports whether err was one
+func (c *ossClock) sync(err error) bool {
+	var serviceErr oss.ServiceError
+	if !errors.As(err, &serviceErr) || serviceErr.Code != "RequestTimeTooSkewed" {
+		return false
+	}
+	var body struct {
+		ServerTime string `xml:"ServerTime"`
+	}
+	if xml.Unmarshal([]byte(serviceErr.RawMessage), &body) != nil {
+		return false
+	}
+	serverTime, ok := parseTime(body.ServerTime)
+	if !ok {
+		return false
+	}
+	c.offset.Store(int64(time.Until(serverTime)))
+	return true
+}
+
+// withOSSClock calls fn with the options dating its oss request,
+// and once more if oss rejected the date as too skewed
+func (d *Pan115) withOSSClock(ctx context.Context, what string, fn func(opts ...oss.Option) error) error {
+	err := fn(d.ossClock.options()...)
+	if d.ossClock.sync(err) {
+		logger(ctx).Warnf("%s: local clock is %v off from oss, retrying with oss time", what, -time.Duration(d.ossClock.offset.Load()))
+		err = fn(d.ossClock.options()...)
+	}
+	d.dropOSSToken(err)
+	return err
+}
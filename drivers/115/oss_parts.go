@@ -0,0 +1,76 @@
+package _115
+
+import (
+	"context"
+	"strings"
+
+	driver115 "github.com/SheltonZhu/115driver/pkg/driver"
+	"github.com/aliyun/aliyun-oss-go-sdk/oss"
+	"github.com/pkg/errors"
+)
+
+// isInvalidPart reports whether oss refused to complete a multipart upload
+// because a listed part doesn't match what it holds
+func isInvalidPart(err error) bool {
+	var serviceErr oss.ServiceError
+	return errors.As(err, &serviceErr) && serviceErr.Code == "InvalidPart"
+}
+
+// uploadedETags gets the ETag of every part oss holds for imur, by part number
+func uploadedETags(bucket *oss.Bucket, imur oss.InitiateMultipartUploadResult, opts ...oss.Option) (map[int]string, error) {
+	etags := map[int]string{}
+	for marker := 0; ; {
+		res, err := bucket.ListUploadedParts(imur, append(opts, oss.PartNumberMarker(marker))...)
+		if err != nil {
+			return nil, err
+		}
+		for _, part := range res.UploadedParts {
+			etags[part.PartNumber] = part.ETag
+			marker = part.PartNumber
+		}
+		if !res.IsTruncated || len(res.UploadedParts) == 0 {
+			return etags, nil
+		}
+	}
+}
+
+func sameETag(a, b string) bool {
+	return strings.EqualFold(strings.Trim(a, `"`), strings.Trim(b, `"`))
+}
+
+// repairParts compares the parts recorded while uploading with those oss holds and
+// uploads the chunks of the missing or mismatched ones again, returning the updated parts
+func (d *Pan115) repairParts(ctx context.Context, bucket *oss.Bucket, imur oss.InitiateMultipartUploadResult,
+	token *driver115.UploadOSSTokenResp, parts []oss.UploadPart, chunks []oss.FileChunk,
+	upload func(chunk oss.FileChunk) (oss.UploadPart, error)) ([]oss.UploadPart, error) {
+	var etags map[int]string
+	if err := d.withOSSClock(ctx, "list parts of "+imur.Key, func(opts ...oss.Option) (err error) {
+		etags, err = uploadedETags(bucket, imur, append([]oss.Option{
+			oss.SetHeader(driver115.OssSecurityTokenHeaderName, token.SecurityToken),
+			oss.UserAgentHeader(driver115.OSSUserAgent),
+		}, opts...)...)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+	recorded := make(map[int]string, len(parts))
+	for _, part := range parts {
+		recorded[part.PartNumber] = part.ETag
+	}
+	repaired := make([]oss.UploadPart, 0, len(chunks))
+	for _, chunk := range chunks {
+		etag, ok := etags[chunk.Number]
+		if ok && sameETag(etag, recorded[chunk.Number]) {
+			repaired = append(repaired, oss.UploadPart{PartNumber: chunk.Number, ETag: recorded[chunk.Number]})
+			continue
+		}
+		logger(ctx).Warnf("part %d of %s is %q on oss but %q was uploaded, uploading it again",
+			chunk.Number, imur.Key, etag, recorded[chunk.Number])
+		part, err := upload(chunk)
+		if err != nil {
+			return nil, errors.Wrapf(err, "upload part %d again", chunk.Number)
+		}
+		repaired = append(repaired, part)
+	}
+	return repaired, nil
+}
@@ -0,0 +1,54 @@
+package _115
+
+import (
+	"time"
+
+	driver115 "github.com/SheltonZhu/115driver/pkg/driver"
+	"github.com/Xhofe/go-cache"
+	"github.com/alist-org/alist/v3/pkg/utils"
+	"github.com/aliyun/aliyun-oss-go-sdk/oss"
+	"github.com/pkg/errors"
+)
+
+// ossTokenTTL is how long a fetched oss token is reused, far below the hour it is valid
+const ossTokenTTL = 5 * time.Minute
+
+const ossTokenKey = "oss_token"
+
+// ossAuthErrCodes are the oss error codes of a token that can't be used anymore
+var ossAuthErrCodes = []string{"InvalidAccessKeyId", "SecurityTokenExpired", "AccessDenied", "InvalidSecurityToken"}
+
+// getOSSToken gets the oss credentials for an upload. With ReuseOSSToken on, the uploads
+// of the next ossTokenTTL share them instead of fetching their own.
+func (d *Pan115) getOSSToken() (*driver115.UploadOSSTokenResp, error) {
+	if d.ReuseOSSToken {
+		if token, ok := d.ossTokens.Get(ossTokenKey); ok {
+			return token, nil
+		}
+	}
+	token, err := d.client.Load().GetOSSToken()
+	if err != nil {
+		return nil, err
+	}
+	d.masker.add(token.AccessKeyID, token.AccessKeySecret, token.SecurityToken)
+	if d.ReuseOSSToken {
+		ttl := ossTokenTTL
+		if !token.Expiration.IsZero() {
+			// leave a minute for the upload that gets it last
+			ttl = min(ttl, time.Until(token.Expiration)-time.Minute)
+		}
+		if ttl > 0 {
+			d.ossTokens.Set(ossTokenKey, token, cache.WithEx[*driver115.UploadOSSTokenResp](ttl))
+		}
+	}
+	return token, nil
+}
+
+// dropOSSToken stops reusing the oss token when err shows oss or 115 no longer accept it
+func (d *Pan115) dropOSSToken(err error) {
+	var serviceErr oss.ServiceError
+	if errors.Is(err, ErrAuthExpired) ||
+		errors.As(err, &serviceErr) && utils.SliceContains(ossAuthErrCodes, serviceErr.Code) {
+		d.ossTokens.Del(ossTokenKey)
+	}
+}
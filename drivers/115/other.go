@@ -0,0 +1,268 @@
+package _115
+
+import (
+	"context"
+	"time"
+
+	"github.com/alist-org/alist/v3/internal/errs"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/pkg/utils"
+	"github.com/pkg/errors"
+)
+
+func (d *Pan115) Other(ctx context.Context, args model.OtherArgs) (res interface{}, err error) {
+	defer func() { err = d.maskErr(err) }()
+	ctx = withReqID(ctx)
+	switch args.Method {
+	case "prune_empty_folders":
+		var data struct {
+			DryRun bool `json:"dry_run"`
+		}
+		if err := decodeOtherData(args.Data, &data); err != nil {
+			return nil, err
+		}
+		err = d.mutate(ctx, (*model.User).CanRemove, []model.Obj{args.Obj}, func() (err error) {
+			res, err = d.PruneEmptyFolders(ctx, args.Obj.GetID(), data.DryRun)
+			return err
+		})
+		return res, err
+	case "star_search_results":
+		var data struct {
+			Keyword string `json:"keyword"`
+		}
+		if err := decodeOtherData(args.Data, &data); err != nil {
+			return nil, err
+		}
+		if data.Keyword == "" {
+			return nil, errors.New("keyword is required")
+		}
+		var count int
+		err := d.mutate(ctx, (*model.User).CanWrite, nil, func() (err error) {
+			count, err = d.StarSearchResults(ctx, data.Keyword)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		return map[string]int{"count": count}, nil
+	case "safe_delete":
+		err = d.mutate(ctx, (*model.User).CanRemove, []model.Obj{args.Obj}, func() (err error) {
+			res, err = d.SafeRemove(ctx, args.Obj)
+			return err
+		})
+		return res, err
+	case "restore_recycled":
+		var data struct {
+			RecycleIDs []string `json:"recycle_ids"`
+		}
+		if err := decodeOtherData(args.Data, &data); err != nil {
+			return nil, err
+		}
+		if len(data.RecycleIDs) == 0 {
+			return nil, errors.New("recycle_ids is required")
+		}
+		// recycle ids aren't tied to the mount, they can put back files from anywhere in the account
+		return nil, d.mutate(ctx, (*model.User).IsAdmin, nil, func() error {
+			return d.RestoreRecycled(ctx, data.RecycleIDs...)
+		})
+	case "list_by_ext":
+		var data struct {
+			Exts []string `json:"exts"`
+		}
+		if err := decodeOtherData(args.Data, &data); err != nil {
+			return nil, err
+		}
+		return d.ListByExt(ctx, args.Obj.GetID(), data.Exts...)
+	case "list_recursive":
+		type entry struct {
+			Path string   `json:"path"`
+			File *FileObj `json:"file"`
+		}
+		files, errFn := d.ListRecursive(ctx, args.Obj.GetID())
+		var entries []entry
+		for path, f := range files {
+			entries = append(entries, entry{Path: path, File: f})
+		}
+		return entries, errFn()
+	case "list_modified_between":
+		var data struct {
+			Since time.Time `json:"since"`
+			Until time.Time `json:"until"`
+		}
+		if err := decodeOtherData(args.Data, &data); err != nil {
+			return nil, err
+		}
+		return d.ListModifiedBetween(ctx, args.Obj.GetID(), data.Since, data.Until)
+	case "list_devices":
+		return d.ListDevices(ctx)
+	case "revoke_device":
+		var data struct {
+			DeviceID string `json:"device_id"`
+		}
+		if err := decodeOtherData(args.Data, &data); err != nil {
+			return nil, err
+		}
+		if data.DeviceID == "" {
+			return nil, errors.New("device_id is required")
+		}
+		// signs out sessions of the whole account, not something a user of one mount decides
+		err = d.mutate(ctx, (*model.User).IsAdmin, nil, func() (err error) {
+			res, err = d.RevokeDevice(ctx, data.DeviceID)
+			return err
+		})
+		return res, err
+	case "reconcile":
+		var data struct {
+			Depth int `json:"depth"`
+		}
+		if err := decodeOtherData(args.Data, &data); err != nil {
+			return nil, err
+		}
+		return d.Reconcile(ctx, args.Obj.GetID(), data.Depth)
+	case "speed_test":
+		var data struct {
+			Limit int64 `json:"limit"`
+		}
+		if err := decodeOtherData(args.Data, &data); err != nil {
+			return nil, err
+		}
+		return d.SpeedTest(ctx, args.Obj, data.Limit)
+	case "cancel_offline_task", "delete_offline_task":
+		var data struct {
+			TaskID      string `json:"task_id"`
+			DeleteFiles bool   `json:"delete_files"`
+		}
+		if err := decodeOtherData(args.Data, &data); err != nil {
+			return nil, err
+		}
+		if data.TaskID == "" {
+			return nil, errors.New("task_id is required")
+		}
+		allowed := (*model.User).CanAddOfflineDownloadTasks
+		if data.DeleteFiles {
+			allowed = func(u *model.User) bool { return u.CanAddOfflineDownloadTasks() && u.CanRemove() }
+		}
+		err = d.mutate(ctx, allowed, nil, func() (err error) {
+			if args.Method == "cancel_offline_task" {
+				res, err = d.CancelOfflineTask(ctx, data.TaskID)
+				return err
+			}
+			res, err = d.DeleteOfflineTask(ctx, data.TaskID, data.DeleteFiles)
+			return err
+		})
+		return res, err
+	case "batch_download_to_local":
+		var data struct {
+			FileIDs  []string `json:"file_ids"`
+			LocalDir string   `json:"local_dir"`
+		}
+		if err := decodeOtherData(args.Data, &data); err != nil {
+			return nil, err
+		}
+		if len(data.FileIDs) == 0 {
+			return nil, errors.New("file_ids is required")
+		}
+		// writes into the filesystem of the server, whoever may read the mount mustn't decide that
+		err = d.mutate(ctx, (*model.User).IsAdmin, nil, func() (err error) {
+			res, err = d.QueueLocalDownload(ctx, data.FileIDs, data.LocalDir)
+			return err
+		})
+		return res, err
+	case "upload_tasks":
+		// the tasks are of the whole storage, not only of the path asked for
+		if err := checkUser(ctx, (*model.User).CanWrite); err != nil {
+			return nil, err
+		}
+		return d.UploadTasks(), nil
+	case "local_download_tasks":
+		if err := checkUser(ctx, (*model.User).IsAdmin); err != nil {
+			return nil, err
+		}
+		return d.LocalDownloadTasks(), nil
+	case "make_dir_all":
+		var data struct {
+			Path string `json:"path"`
+		}
+		if err := decodeOtherData(args.Data, &data); err != nil {
+			return nil, err
+		}
+		err = d.mutate(ctx, (*model.User).CanWrite, []model.Obj{args.Obj}, func() (err error) {
+			res, err = d.MakeDirAll(ctx, args.Obj.GetID(), data.Path)
+			return err
+		})
+		return res, err
+	case "capabilities":
+		var data struct {
+			Refresh bool `json:"refresh"`
+		}
+		if err := decodeOtherData(args.Data, &data); err != nil {
+			return nil, err
+		}
+		if caps := d.Capabilities(); caps != nil && !data.Refresh {
+			return caps, nil
+		}
+		return d.ProbeCapabilities(ctx)
+	case "touch":
+		var data struct {
+			ModTime time.Time `json:"mod_time"`
+		}
+		if err := decodeOtherData(args.Data, &data); err != nil {
+			return nil, err
+		}
+		if data.ModTime.IsZero() {
+			return nil, errors.New("mod_time is required")
+		}
+		err = d.mutate(ctx, (*model.User).CanWrite, []model.Obj{args.Obj}, func() (err error) {
+			res, err = d.Touch(ctx, args.Obj, data.ModTime)
+			return err
+		})
+		return res, err
+	case "list_smart":
+		var filter SmartFilter
+		if err := decodeOtherData(args.Data, &filter); err != nil {
+			return nil, err
+		}
+		if filter.DirID == "" {
+			filter.DirID = args.Obj.GetID()
+		}
+		return d.ListSmart(ctx, filter)
+	case "video_preview":
+		return d.GetVideoPlayInfo(ctx, args.Obj.(*FileObj).PickCode)
+	default:
+		return nil, errs.NotSupport
+	}
+}
+
+// mutate runs fn for an other action that changes something on 115. The other api only
+// checks that the user can read the path, so the user has to pass allowed as well, objs
+// can't be read-only smart folders and fn runs once more after a relogin when the session expired.
+func (d *Pan115) mutate(ctx context.Context, allowed func(*model.User) bool, objs []model.Obj, fn func() error) error {
+	if err := checkUser(ctx, allowed); err != nil {
+		return err
+	}
+	if err := checkWritable(objs...); err != nil {
+		return err
+	}
+	return d.withRelogin(ctx, fn)
+}
+
+// checkUser fails with errs.PermissionDenied unless the user of ctx passes allowed
+func checkUser(ctx context.Context, allowed func(*model.User) bool) error {
+	user, _ := ctx.Value("user").(*model.User)
+	if user == nil || !allowed(user) {
+		return errors.WithStack(errs.PermissionDenied)
+	}
+	return nil
+}
+
+// decodeOtherData converts the loosely typed data of an other request into v
+func decodeOtherData(data interface{}, v interface{}) error {
+	if data == nil {
+		return nil
+	}
+	b, err := utils.Json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	return utils.Json.Unmarshal(b, v)
+}
@@ -0,0 +1,17 @@
+package _115
+
+import (
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/pkg/utils"
+)
+
+// applyPlaybackChunk makes the proxy fetch link in parts of PlaybackChunkSize, so an open-ended
+// range of a player is read from the cdn a part at a time as it is consumed instead of in one
+// request to the end of the file. The parts are spread over link.Concurrency threads, a part
+// size the link has already is kept.
+func (d *Pan115) applyPlaybackChunk(link *model.Link) {
+	if d.PlaybackChunkSize <= 0 || link.PartSize != 0 {
+		return
+	}
+	link.PartSize = int(d.PlaybackChunkSize * utils.MB)
+}
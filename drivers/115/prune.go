@@ -0,0 +1,100 @@
+package _115
+
+import (
+	"context"
+	stdpath "path"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+type PruneResult struct {
+	DryRun  bool     `json:"dry_run"`
+	Count   int      `json:"count"`
+	Removed []string `json:"removed"`
+}
+
+// parseProtectedFolders reads the comma separated folder ids of protected_folders
+func parseProtectedFolders(s string) map[string]struct{} {
+	protected := map[string]struct{}{}
+	for _, id := range strings.Split(s, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			protected[id] = struct{}{}
+		}
+	}
+	return protected
+}
+
+// checkProtected refuses to delete the folders of protected_folders, deleting
+// a folder above one isn't caught here, only PruneEmptyFolders keeps those
+func (d *Pan115) checkProtected(ids ...string) error {
+	for _, id := range ids {
+		if _, ok := d.protected[id]; ok {
+			return errors.Wrapf(ErrProtectedFolder, "%s", id)
+		}
+	}
+	return nil
+}
+
+// PruneEmptyFolders removes every folder under dirID that holds no file, directly or in
+// its subfolders. dirID itself is kept, and so are protected folders and the folders
+// above them. Only the topmost empty folders are sent to 115 since deleting a folder
+// takes its empty subfolders with it.
+func (d *Pan115) PruneEmptyFolders(ctx context.Context, dirID string, dryRun bool) (*PruneResult, error) {
+	type dirNode struct {
+		id, path, parent string
+		// keep is set for folders holding a file or a protected folder
+		keep bool
+	}
+	// folders are told apart by id, 115 lets siblings share a name
+	root := &dirNode{id: dirID, path: "/", keep: true}
+	nodes := map[string]*dirNode{root.id: root}
+	var dirs []*dirNode
+	err := d.walk(ctx, dirID, func(dirPath string, f *FileObj) error {
+		parent, ok := nodes[f.ParentID]
+		if !ok {
+			return errors.Errorf("parent %s of %s isn't in the walked tree", f.ParentID, stdpath.Join(dirPath, f.GetName()))
+		}
+		if !f.IsDir() {
+			parent.keep = true
+			return nil
+		}
+		node := &dirNode{id: f.GetID(), path: stdpath.Join(dirPath, f.GetName()), parent: parent.id}
+		if d.checkProtected(node.id) != nil {
+			node.keep = true
+		}
+		nodes[node.id] = node
+		dirs = append(dirs, node)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// walk is depth first, so going backwards visits children before their parents
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if node := dirs[i]; node.keep {
+			nodes[node.parent].keep = true
+		}
+	}
+	res := &PruneResult{DryRun: dryRun, Removed: []string{}}
+	var ids []string
+	for _, node := range dirs {
+		if node.keep {
+			continue
+		}
+		res.Removed = append(res.Removed, node.path)
+		if nodes[node.parent].keep {
+			ids = append(ids, node.id)
+		}
+	}
+	res.Count = len(res.Removed)
+	if dryRun || len(ids) == 0 {
+		return res, nil
+	}
+	if _, err := d.batchRemove(ctx, ids); err != nil {
+		return nil, err
+	}
+	logger(ctx).Infof("pruned %d empty folders under %s", res.Count, dirID)
+	return res, nil
+}
@@ -0,0 +1,159 @@
+package _115
+
+import (
+	"context"
+	stdpath "path"
+	"sort"
+	"time"
+
+	"github.com/Xhofe/go-cache"
+	"github.com/alist-org/alist/v3/internal/op"
+	"github.com/pkg/errors"
+)
+
+type ReconcileChange struct {
+	ID    string `json:"id"`
+	Path  string `json:"path"`
+	From  string `json:"from,omitempty"`
+	IsDir bool   `json:"is_dir"`
+}
+
+// ReconcileDiff is what changed on 115 since alist listed the folders,
+// paths are relative to the reconciled folder
+type ReconcileDiff struct {
+	Added   []ReconcileChange `json:"added"`
+	Removed []ReconcileChange `json:"removed"`
+	Moved   []ReconcileChange `json:"moved"`
+}
+
+type reconcileEntry struct {
+	path  string
+	isDir bool
+}
+
+// recordView keeps the listing of dirID for as long as alist caches it, Reconcile diffs against it
+func (d *Pan115) recordView(dirID string, files []FileObj) {
+	if d.CacheExpiration > 0 {
+		d.views.Set(dirID, files, cache.WithEx[[]FileObj](time.Minute*time.Duration(d.CacheExpiration)))
+	}
+}
+
+// viewPath finds the path of dirID in the storage by following the recorded listings down
+// from the root, a folder alist hasn't listed the way to isn't found
+func (d *Pan115) viewPath(dirID string) (string, bool) {
+	rootID := d.GetRootId()
+	seen := map[string]bool{}
+	var find func(id, p string) (string, bool)
+	find = func(id, p string) (string, bool) {
+		if id == dirID {
+			return p, true
+		}
+		files, ok := d.views.Get(id)
+		if !ok || seen[id] {
+			return "", false
+		}
+		seen[id] = true
+		for _, f := range files {
+			if !f.IsDir() {
+				continue
+			}
+			if found, ok := find(f.GetID(), stdpath.Join(p, f.GetName())); ok {
+				return found, true
+			}
+		}
+		return "", false
+	}
+	return find(rootID, "/")
+}
+
+// Reconcile lists the subtree of dirID again down to depth levels, MaxDepth when depth is 0,
+// and returns how it differs from the listings alist has cached. The cached listings under
+// dirID are dropped along with the folder sizes, so alist shows the new state right away.
+// Only folders alist had listed report added entries, the rest were never seen anyway.
+func (d *Pan115) Reconcile(ctx context.Context, dirID string, depth int) (*ReconcileDiff, error) {
+	if depth <= 0 {
+		depth = d.MaxDepth
+	}
+	if depth <= 0 {
+		depth = defaultMaxDepth
+	}
+
+	// looked up before the listings below are recorded again
+	dirPath, cached := d.viewPath(dirID)
+	old := map[string]reconcileEntry{}
+	viewed := map[string]bool{}
+	var walkView func(dirID, dirPath string, level int)
+	walkView = func(dirID, dirPath string, level int) {
+		files, ok := d.views.Get(dirID)
+		if !ok || viewed[dirID] {
+			return
+		}
+		viewed[dirID] = true
+		for _, f := range files {
+			p := stdpath.Join(dirPath, f.GetName())
+			old[f.GetID()] = reconcileEntry{path: p, isDir: f.IsDir()}
+			if f.IsDir() && level < depth {
+				walkView(f.GetID(), p, level+1)
+			}
+		}
+	}
+	walkView(dirID, "/", 1)
+
+	diff := &ReconcileDiff{Added: []ReconcileChange{}, Removed: []ReconcileChange{}, Moved: []ReconcileChange{}}
+	current := map[string]struct{}{}
+	visited := map[string]struct{}{dirID: {}}
+	var list func(dirID, dirPath string, level int) error
+	list = func(dirID, dirPath string, level int) error {
+		if err := d.WaitLimit(ctx); err != nil {
+			return err
+		}
+		files, err := d.getFiles(ctx, dirID)
+		if err != nil {
+			return err
+		}
+		d.recordView(dirID, files)
+		d.folderSizes.Del(dirID)
+		for _, f := range files {
+			p := stdpath.Join(dirPath, f.GetName())
+			current[f.GetID()] = struct{}{}
+			change := ReconcileChange{ID: f.GetID(), Path: p, IsDir: f.IsDir()}
+			if prev, ok := old[f.GetID()]; ok {
+				if prev.path != p {
+					change.From = prev.path
+					diff.Moved = append(diff.Moved, change)
+				}
+			} else if viewed[dirID] {
+				diff.Added = append(diff.Added, change)
+			}
+			if !f.IsDir() || level >= depth {
+				continue
+			}
+			if _, ok := visited[f.GetID()]; ok {
+				return errors.Wrap(ErrCyclicDir, p)
+			}
+			visited[f.GetID()] = struct{}{}
+			if err := list(f.GetID(), p, level+1); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := list(dirID, "/", 1); err != nil {
+		return nil, err
+	}
+	for id, prev := range old {
+		if _, ok := current[id]; !ok {
+			diff.Removed = append(diff.Removed, ReconcileChange{ID: id, Path: prev.path, IsDir: prev.isDir})
+		}
+	}
+	for _, changes := range [][]ReconcileChange{diff.Added, diff.Removed, diff.Moved} {
+		sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	}
+
+	if cached {
+		op.ClearCache(d, dirPath)
+	}
+	logger(ctx).Infof("reconciled %s: %d added, %d removed, %d moved",
+		dirID, len(diff.Added), len(diff.Removed), len(diff.Moved))
+	return diff, nil
+}
@@ -0,0 +1,92 @@
+package _115
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"time"
+
+	driver115 "github.com/SheltonZhu/115driver/pkg/driver"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/pkg/errors"
+)
+
+// recycleLookupLimit is how many of the latest recycle bin entries are searched for a deleted item
+const recycleLookupLimit = 100
+
+// recycleClockSkew is how far the clock of 115 may be behind ours when matching delete times
+const recycleClockSkew = time.Minute
+
+type RecycleEntry struct {
+	RecycleID string    `json:"recycle_id"`
+	Name      string    `json:"name"`
+	ParentID  string    `json:"parent_id"`
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+// SafeRemove deletes obj and makes sure it is in the recycle bin afterwards,
+// the returned entry can be passed to RestoreRecycled to undo the delete
+func (d *Pan115) SafeRemove(ctx context.Context, obj model.Obj) (*RecycleEntry, error) {
+	if err := checkWritable(obj); err != nil {
+		return nil, err
+	}
+	if err := d.checkProtected(obj.GetID()); err != nil {
+		return nil, err
+	}
+	if err := d.WaitLimit(ctx); err != nil {
+		return nil, err
+	}
+	deletedAt := time.Now()
+	if err := d.client.Load().Delete(obj.GetID()); err != nil {
+		return nil, err
+	}
+	entry, err := d.findRecycled(ctx, obj, deletedAt)
+	if err != nil {
+		logger(ctx).Warnf("%s was deleted but is not recoverable: %v", obj.GetName(), err)
+		return nil, err
+	}
+	logger(ctx).Infof("%s moved to recycle bin as %s", obj.GetName(), entry.RecycleID)
+	return entry, nil
+}
+
+// findRecycled looks for obj among the latest recycle bin entries. The bin doesn't keep the
+// id of the file, so the entry is the newest one of its name, folder and size deleted since
+// the delete call, older entries of a file with the same name are never taken for it.
+func (d *Pan115) findRecycled(ctx context.Context, obj model.Obj, since time.Time) (*RecycleEntry, error) {
+	if err := d.WaitLimit(ctx); err != nil {
+		return nil, err
+	}
+	items, err := d.client.Load().ListRecycleBin(0, recycleLookupLimit)
+	if err != nil {
+		return nil, errors.Wrap(ErrNotRecoverable, err.Error())
+	}
+	var parentID string
+	if f, ok := obj.(*FileObj); ok {
+		parentID = f.ParentID
+	}
+	var entry *RecycleEntry
+	for _, item := range items {
+		if item.FileName != obj.GetName() ||
+			parentID != "" && string(item.ParentId) != parentID ||
+			!obj.IsDir() && int64(item.FileSize) != obj.GetSize() {
+			continue
+		}
+		deletedAt, ok := parseTime(strconv.FormatInt(int64(item.DeleteTime), 10))
+		if !ok || deletedAt.Before(since.Add(-recycleClockSkew)) || entry != nil && !deletedAt.After(entry.DeletedAt) {
+			continue
+		}
+		entry = &RecycleEntry{RecycleID: item.FileId, Name: item.FileName, ParentID: string(item.ParentId), DeletedAt: deletedAt}
+	}
+	if entry == nil {
+		return nil, errors.Wrap(ErrNotRecoverable, obj.GetName())
+	}
+	return entry, nil
+}
+
+// RestoreRecycled puts recycle bin entries back where they were deleted from
+func (d *Pan115) RestoreRecycled(ctx context.Context, recycleIDs ...string) error {
+	_, err := d.inBatches(ctx, batchRestore, recycleIDs, func(ids []string) error {
+		return d.postForm(ctx, driver115.ApiRecycleRevert, indexedForm(url.Values{}, "rid", ids))
+	})
+	return err
+}
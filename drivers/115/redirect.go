@@ -0,0 +1,73 @@
+package _115
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/alist-org/alist/v3/internal/model"
+)
+
+// hosts115 are the domains of 115 and its cdn, the cookie and referer of a request go to no other host
+var hosts115 = []string{"115.com", "115cdn.com", "115cdn.net"}
+
+// redirectKeepHeaders are the headers a redirect to a host outside of 115 keeps
+var redirectKeepHeaders = []string{"User-Agent", "Range", "Accept", "Accept-Encoding"}
+
+func is115Host(host string) bool {
+	host = strings.ToLower(host)
+	for _, h := range hosts115 {
+		if host == h || strings.HasSuffix(host, "."+h) {
+			return true
+		}
+	}
+	return false
+}
+
+// redirectHeaders sets the headers of a redirected request from the first one of the chain:
+// every one of them to a 115 host, where Go would have dropped the cookie going from one
+// subdomain to another, and only redirectKeepHeaders to any other host
+func redirectHeaders(req *http.Request, via []*http.Request) {
+	first := via[0].Header
+	if is115Host(req.URL.Hostname()) {
+		req.Header = first.Clone()
+		return
+	}
+	req.Header = http.Header{}
+	for _, k := range redirectKeepHeaders {
+		if v, ok := first[k]; ok {
+			req.Header[k] = v
+		}
+	}
+}
+
+// resolveRedirects follows the redirects of the download url of link and hands out the url
+// they end at, with the headers redirectHeaders picked for its host, so whoever downloads it
+// doesn't have to follow them with the 115 headers. The link is kept when that fails.
+func (d *Pan115) resolveRedirects(ctx context.Context, link *model.Link) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, link.URL, nil)
+	if err != nil {
+		return
+	}
+	req.Header = link.Header.Clone()
+	if req.Header == nil {
+		req.Header = http.Header{}
+	}
+	req.Header.Set("Range", "bytes=0-0")
+	resp, err := d.client.Load().Client.GetClient().Do(req)
+	if err != nil {
+		logger(ctx).Warnf("follow redirects of download url failed, keeping it: %v", err)
+		return
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode >= http.StatusBadRequest {
+		logger(ctx).Warnf("redirects of download url end at %s, keeping it", resp.Status)
+		return
+	}
+	if final := resp.Request; final.URL.String() != link.URL {
+		logger(ctx).Debugf("download url redirects to %s", final.URL.Host)
+		link.URL = final.URL.String()
+		link.Header = final.Header.Clone()
+		link.Header.Del("Range")
+	}
+}
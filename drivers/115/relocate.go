@@ -0,0 +1,183 @@
+package _115
+
+import (
+	"context"
+	"net/http"
+	stdpath "path"
+
+	driver115 "github.com/SheltonZhu/115driver/pkg/driver"
+	"github.com/alist-org/alist/v3/drivers/base"
+	"github.com/alist-org/alist/v3/internal/driver"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/stream"
+	"github.com/pkg/errors"
+)
+
+const (
+	RelocateRapid   = "rapid"
+	RelocateUpload  = "upload"
+	RelocateSkipped = "skipped"
+)
+
+type RelocateResult struct {
+	Path   string `json:"path"`
+	FileID string `json:"file_id"`
+	Method string `json:"method"`
+	Error  string `json:"error,omitempty"`
+}
+
+type RelocateReport struct {
+	Files  []RelocateResult `json:"files"`
+	Failed int              `json:"failed"`
+}
+
+// relocated returns the source file ids that already made it to the destination
+func (r *RelocateReport) relocated() map[string]bool {
+	done := map[string]bool{}
+	if r == nil {
+		return done
+	}
+	for _, f := range r.Files {
+		if f.Error == "" {
+			done[f.FileID] = true
+		}
+	}
+	return done
+}
+
+// relocateSource and relocateTarget are the parts of Pan115 that relocate needs
+type relocateSource interface {
+	walk(ctx context.Context, dirID string, fn walkFunc) error
+	Link(ctx context.Context, file model.Obj, args model.LinkArgs) (*model.Link, error)
+}
+
+type relocateTarget interface {
+	List(ctx context.Context, dir model.Obj, args model.ListArgs) ([]model.Obj, error)
+	MakeDir(ctx context.Context, parentDir model.Obj, dirName string) (model.Obj, error)
+	Put(ctx context.Context, dstDir model.Obj, file model.FileStreamer, up driver.UpdateProgress) (model.Obj, error)
+	normName(name string) string
+}
+
+type RelocateOptions struct {
+	// Prev is the report of an earlier run, the files it relocated are skipped
+	Prev *RelocateReport
+	// OpID checkpoints the run, running again with the same id skips
+	// the files already relocated even when the process was restarted
+	OpID string
+}
+
+// Relocate copies everything under srcDirID of src into dstDirID of dst, typically to move
+// to another account. Files 115 already has are rapid uploaded without transferring data,
+// the others are downloaded from src and uploaded to dst.
+// Failed files are recorded in the report and skipped, an interrupted or partly failed run
+// is resumed by passing its report or running it with the same OpID again.
+func Relocate(ctx context.Context, src, dst *Pan115, srcDirID, dstDirID string, opts RelocateOptions) (*RelocateReport, error) {
+	return relocate(withReqID(ctx), src, dst, srcDirID, dstDirID, opts)
+}
+
+func relocate(ctx context.Context, src relocateSource, dst relocateTarget, srcDirID, dstDirID string, opts RelocateOptions) (report *RelocateReport, err error) {
+	type entry struct {
+		dirPath string
+		file    *FileObj
+	}
+	var entries []entry
+	err = src.walk(ctx, srcDirID, func(dirPath string, f *FileObj) error {
+		entries = append(entries, entry{dirPath, f})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var cp *checkpoint
+	if opts.OpID != "" {
+		if cp, err = openCheckpoint(opts.OpID); err != nil {
+			return nil, err
+		}
+		defer func() {
+			if err == nil && report.Failed == 0 {
+				_ = cp.finish()
+			} else {
+				_ = cp.close()
+			}
+		}()
+	}
+	done := opts.Prev.relocated()
+	report = &RelocateReport{Files: []RelocateResult{}}
+	dirs := map[string]model.Obj{"/": &model.Object{ID: dstDirID, IsFolder: true}}
+	for _, e := range entries {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		filePath := stdpath.Join(e.dirPath, e.file.GetName())
+		if e.file.IsDir() {
+			dir, err := relocateDir(ctx, dst, dirs[e.dirPath], e.file.GetName())
+			if err != nil {
+				return report, errors.Wrapf(err, "create folder %s", filePath)
+			}
+			dirs[filePath] = dir
+			continue
+		}
+		res := RelocateResult{Path: filePath, FileID: e.file.GetID(), Method: RelocateSkipped}
+		if done[res.FileID] || cp != nil && cp.isDone(res.FileID) {
+			report.Files = append(report.Files, res)
+			continue
+		}
+		res.Method, err = relocateFile(ctx, src, dst, dirs[e.dirPath], e.file)
+		if err != nil {
+			res.Error = err.Error()
+			report.Failed++
+			logger(ctx).Warnf("relocate %s failed: %v", filePath, err)
+		} else if cp != nil {
+			if err := cp.markDone(res.FileID); err != nil {
+				return report, errors.Wrap(err, "save checkpoint")
+			}
+		}
+		report.Files = append(report.Files, res)
+		// nothing will get through until the user steps in, keep the rest for a resumed run
+		if isPauseErr(err) {
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+// relocateDir creates name under parent, or picks up the folder a previous run left there
+func relocateDir(ctx context.Context, dst relocateTarget, parent model.Obj, name string) (model.Obj, error) {
+	dir, err := dst.MakeDir(ctx, parent, name)
+	if err == nil && dir != nil {
+		return dir, nil
+	}
+	if err != nil && !errors.Is(err, driver115.ErrExist) {
+		return nil, err
+	}
+	objs, err := dst.List(ctx, parent, model.ListArgs{})
+	if err != nil {
+		return nil, err
+	}
+	for _, obj := range objs {
+		if obj.IsDir() && dst.normName(obj.GetName()) == dst.normName(name) {
+			return obj, nil
+		}
+	}
+	return nil, errors.Errorf("folder %s not found after creating it", name)
+}
+
+// relocateFile puts f into dstDir, the stream only gets downloaded in full
+// when dst couldn't rapid upload it
+func relocateFile(ctx context.Context, src relocateSource, dst relocateTarget, dstDir model.Obj, f *FileObj) (string, error) {
+	link, err := src.Link(ctx, f, model.LinkArgs{Header: http.Header{"User-Agent": []string{base.UserAgent}}})
+	if err != nil {
+		return RelocateUpload, err
+	}
+	ss, err := stream.NewSeekableStream(stream.FileStream{Ctx: ctx, Obj: f}, link)
+	if err != nil {
+		return RelocateUpload, err
+	}
+	defer ss.Close()
+	_, err = dst.Put(ctx, dstDir, ss, func(float64) {})
+	if ss.GetFile() != nil {
+		return RelocateUpload, err
+	}
+	return RelocateRapid, err
+}
@@ -0,0 +1,102 @@
+package _115
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	driver115 "github.com/SheltonZhu/115driver/pkg/driver"
+)
+
+const (
+	apiFileSearch = "https://webapi.115.com/files/search"
+	apiFileStar   = "https://webapi.115.com/files/star"
+)
+
+// searchPage gets one page of files matching keyword anywhere in the drive
+func (d *Pan115) searchPage(ctx context.Context, keyword string, offset, limit int64) (*driver115.FileListResp, error) {
+	if err := d.WaitLimit(ctx); err != nil {
+		return nil, err
+	}
+	result := driver115.FileListResp{}
+	resp, err := d.client.Load().Client.R().
+		SetContext(ctx).
+		SetQueryParams(map[string]string{
+			"search_value": keyword,
+			"cid":          "0",
+			"offset":       strconv.FormatInt(offset, 10),
+			"limit":        strconv.FormatInt(limit, 10),
+			"format":       "json",
+		}).
+		SetResult(&result).
+		ForceContentType("application/json;charset=UTF-8").
+		Get(apiFileSearch)
+	if err = driver115.CheckErr(err, &result, resp); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Search gets every file matching keyword, page by page
+func (d *Pan115) Search(ctx context.Context, keyword string) ([]FileObj, error) {
+	limit := d.PageSize
+	if limit <= 0 {
+		limit = driver115.FileListLimit
+	}
+	var files []FileObj
+	for offset := int64(0); ; {
+		page, err := d.searchPage(ctx, keyword, offset, limit)
+		if err != nil {
+			return nil, err
+		}
+		for i := range page.Files {
+			files = append(files, toFileObj(&page.Files[i]))
+		}
+		offset += int64(len(page.Files))
+		if len(page.Files) == 0 || offset >= int64(page.Count) {
+			return files, nil
+		}
+	}
+}
+
+// star stars or unstars fileIDs in batches of the star batch size
+func (d *Pan115) star(ctx context.Context, fileIDs []string, star bool) error {
+	value := "0"
+	if star {
+		value = "1"
+	}
+	_, err := d.inBatches(ctx, batchStar, fileIDs, func(ids []string) error {
+		result := driver115.BasicResp{}
+		resp, err := d.client.Load().Client.R().
+			SetContext(ctx).
+			SetFormData(map[string]string{
+				"file_id": strings.Join(ids, ","),
+				"star":    value,
+			}).
+			SetResult(&result).
+			ForceContentType("application/json;charset=UTF-8").
+			Post(apiFileStar)
+		return driver115.CheckErr(err, &result, resp)
+	})
+	return err
+}
+
+// StarSearchResults stars every file matching keyword, returns how many were starred.
+// Files that are starred already are left alone and not counted.
+func (d *Pan115) StarSearchResults(ctx context.Context, keyword string) (int, error) {
+	files, err := d.Search(ctx, keyword)
+	if err != nil {
+		return 0, err
+	}
+	var ids []string
+	for _, f := range files {
+		if !f.Star {
+			ids = append(ids, f.GetID())
+		}
+	}
+	if err := d.star(ctx, ids, true); err != nil {
+		return 0, err
+	}
+	logger(ctx).Infof("starred %d files matching %q", len(ids), keyword)
+	return len(ids), nil
+}
@@ -0,0 +1,202 @@
+package _115
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	driver115 "github.com/SheltonZhu/115driver/pkg/driver"
+	"github.com/alist-org/alist/v3/internal/conf"
+	"github.com/alist-org/alist/v3/internal/errs"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/pkg/utils"
+	"github.com/pkg/errors"
+)
+
+// smartFolderPrefix marks the ids of the folders SmartFolders adds at the root
+const smartFolderPrefix = "smart:"
+
+// SmartFilter picks files by type, star and modification time. Saved in SmartFolders it
+// shows as a read-only folder at the root of the storage.
+type SmartFilter struct {
+	Name string `json:"name"`
+	// DirID is the folder looked in, the root of the storage when empty
+	DirID string `json:"dir_id"`
+	// Recursive looks in the subfolders of DirID too
+	Recursive bool `json:"recursive"`
+	// Type is one of video, audio, image and doc, empty for any
+	Type    string `json:"type"`
+	Starred bool   `json:"starred"`
+	// Modified is today, this_week, this_month or this_year, empty for any time
+	Modified string `json:"modified"`
+}
+
+// smartTypes are the types of SmartFilter with the type 115 filters listings by
+// and the type the settings of alist give the file extensions
+var smartTypes = map[string]struct {
+	code    string
+	objType int
+}{
+	"doc":   {"1", conf.TEXT},
+	"image": {"2", conf.IMAGE},
+	"audio": {"3", conf.AUDIO},
+	"video": {"4", conf.VIDEO},
+}
+
+func (f *SmartFilter) validate() error {
+	if _, ok := smartTypes[f.Type]; f.Type != "" && !ok {
+		return fmt.Errorf("unknown file type %q of filter %s", f.Type, f.Name)
+	}
+	if _, ok := f.since(time.Now()); !ok {
+		return fmt.Errorf("unknown modified %q of filter %s", f.Modified, f.Name)
+	}
+	return nil
+}
+
+// since is the start of the Modified window at now, in the timezone of 115
+func (f *SmartFilter) since(now time.Time) (time.Time, bool) {
+	now = now.In(cst)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, cst)
+	switch f.Modified {
+	case "":
+		return time.Time{}, true
+	case "today":
+		return today, true
+	case "this_week":
+		// weeks start on monday
+		return today.AddDate(0, 0, -(int(today.Weekday())+6)%7), true
+	case "this_month":
+		return today.AddDate(0, 0, 1-today.Day()), true
+	case "this_year":
+		return today.AddDate(0, 0, 1-today.YearDay()), true
+	}
+	return time.Time{}, false
+}
+
+// query is what 115 can filter by itself, nil when nothing
+func (f *SmartFilter) query() map[string]string {
+	query := map[string]string{}
+	if f.Type != "" {
+		query["type"] = smartTypes[f.Type].code
+	}
+	if f.Starred {
+		query["star"] = "1"
+	}
+	if len(query) == 0 {
+		return nil
+	}
+	return query
+}
+
+// match checks file on every condition, also on a filtered listing as 115 silently ignores
+// filters it doesn't know
+func (f *SmartFilter) match(file *FileObj, since time.Time) bool {
+	if file.IsDir() {
+		return false
+	}
+	if f.Type != "" && utils.GetFileType(file.GetName()) != smartTypes[f.Type].objType {
+		return false
+	}
+	if f.Starred && !file.Star {
+		return false
+	}
+	return inWindow(file.ModTime(), since, time.Time{})
+}
+
+// ListSmart lists the files f picks. Directly under a folder 115 filters the listing by type
+// and star, the other conditions are checked after. Looking into subfolders goes through
+// ListRecursive and checks every condition itself, a filtered listing would hide the folders.
+func (d *Pan115) ListSmart(ctx context.Context, f SmartFilter) ([]FileObj, error) {
+	if err := f.validate(); err != nil {
+		return nil, err
+	}
+	since, _ := f.since(time.Now())
+	dirID := f.DirID
+	if dirID == "" {
+		dirID = d.GetRootId()
+	}
+	var res []FileObj
+	if f.Recursive {
+		files, errFn := d.ListRecursive(ctx, dirID)
+		for _, file := range files {
+			if f.match(file, since) {
+				res = append(res, *file)
+			}
+		}
+		return res, errFn()
+	}
+	if err := d.WaitLimit(ctx); err != nil {
+		return nil, err
+	}
+	files, err := d.listFiles(ctx, dirID, f.query())
+	if err != nil && f.query() != nil {
+		logger(ctx).Debugf("115 can't filter %s for %s, filtering locally: %v", dirID, f.Name, err)
+		if err = d.WaitLimit(ctx); err != nil {
+			return nil, err
+		}
+		files, err = d.getFiles(ctx, dirID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	for i := range files {
+		if f.match(&files[i], since) {
+			res = append(res, files[i])
+		}
+	}
+	return res, nil
+}
+
+// parseSmartFolders reads SmartFolders, a json array of filters with unique names
+func parseSmartFolders(s string) ([]SmartFilter, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	var filters []SmartFilter
+	if err := utils.Json.UnmarshalFromString(s, &filters); err != nil {
+		return nil, errors.Wrap(err, "smart folders aren't a json array of filters")
+	}
+	var names []string
+	for i := range filters {
+		name := filters[i].Name
+		if name == "" || strings.Contains(name, "/") || utils.SliceContains(names, name) {
+			return nil, fmt.Errorf("smart folder %d needs a unique name without /, got %q", i, name)
+		}
+		if err := filters[i].validate(); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return filters, nil
+}
+
+// smartFolders are the folders of SmartFolders as listed at the root
+func (d *Pan115) smartFolders() []FileObj {
+	return utils.MustSliceConvert(d.smartFilters, func(f SmartFilter) FileObj {
+		return FileObj{File: driver115.File{IsDirectory: true, FileID: smartFolderPrefix + f.Name, Name: f.Name}}
+	})
+}
+
+func (d *Pan115) smartFilter(dirID string) (*SmartFilter, bool) {
+	name, ok := strings.CutPrefix(dirID, smartFolderPrefix)
+	if !ok {
+		return nil, false
+	}
+	for i := range d.smartFilters {
+		if d.smartFilters[i].Name == name {
+			return &d.smartFilters[i], true
+		}
+	}
+	return nil, false
+}
+
+// checkWritable refuses changes to smart folders and what is put into them, they only list files
+func checkWritable(objs ...model.Obj) error {
+	for _, obj := range objs {
+		if obj != nil && strings.HasPrefix(obj.GetID(), smartFolderPrefix) {
+			return errors.Wrapf(errs.PermissionDenied, "%s is a read-only smart folder", obj.GetName())
+		}
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package _115
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/alist-org/alist/v3/drivers/base"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/pkg/utils"
+)
+
+// speedTestBytes is how much of the file a speed test downloads by default
+const speedTestBytes = 4 * utils.MB
+
+type SpeedTestResult struct {
+	// APILatency is how long 115 took to hand out the download link
+	APILatency int64 `json:"api_latency_ms"`
+	// CDNLatency is how long the cdn took to answer the download
+	CDNLatency     int64   `json:"cdn_latency_ms"`
+	Bytes          int64   `json:"bytes"`
+	BytesPerSecond float64 `json:"bytes_per_second"`
+}
+
+type linker interface {
+	Link(ctx context.Context, file model.Obj, args model.LinkArgs) (*model.Link, error)
+}
+
+// SpeedTest downloads the first limit bytes of file, speedTestBytes when limit is 0,
+// and reports the latency of the api and the cdn and the throughput reached.
+// Canceling ctx stops the download.
+func (d *Pan115) SpeedTest(ctx context.Context, file model.Obj, limit int64) (*SpeedTestResult, error) {
+	return speedTest(ctx, d, d.client.Load().Client.GetClient(), file, limit)
+}
+
+func speedTest(ctx context.Context, src linker, client *http.Client, file model.Obj, limit int64) (*SpeedTestResult, error) {
+	if limit <= 0 {
+		limit = speedTestBytes
+	}
+	res := &SpeedTestResult{}
+	start := time.Now()
+	link, err := src.Link(ctx, file, model.LinkArgs{Header: http.Header{"User-Agent": []string{base.UserAgent}}})
+	if err != nil {
+		return nil, err
+	}
+	res.APILatency = time.Since(start).Milliseconds()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, link.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range link.Header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=0-%d", limit-1))
+	start = time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusBadRequest {
+		return nil, fmt.Errorf("download answered %s", resp.Status)
+	}
+	res.CDNLatency = time.Since(start).Milliseconds()
+
+	start = time.Now()
+	res.Bytes, err = io.Copy(io.Discard, io.LimitReader(resp.Body, limit))
+	if err != nil {
+		return nil, err
+	}
+	if elapsed := time.Since(start).Seconds(); elapsed > 0 {
+		res.BytesPerSecond = float64(res.Bytes) / elapsed
+	}
+	logger(ctx).Infof("speed test of %s: api %dms, cdn %dms, %d bytes at %.0f B/s",
+		file.GetName(), res.APILatency, res.CDNLatency, res.Bytes, res.BytesPerSecond)
+	return res, nil
+}
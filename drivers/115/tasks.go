@@ -0,0 +1,34 @@
+package _115
+
+import (
+	"github.com/alist-org/alist/v3/internal/task"
+	"github.com/xhofe/tache"
+)
+
+// TaskInfo is the state of a background task of the driver
+type TaskInfo struct {
+	TaskID   string      `json:"task_id"`
+	Name     string      `json:"name"`
+	State    tache.State `json:"state"`
+	Status   string      `json:"status"`
+	Progress float64     `json:"progress"`
+	Error    string      `json:"error,omitempty"`
+}
+
+func taskInfos[T task.TaskExtensionInfo](tasks []T) []TaskInfo {
+	infos := make([]TaskInfo, 0, len(tasks))
+	for _, t := range tasks {
+		info := TaskInfo{
+			TaskID:   t.GetID(),
+			Name:     t.GetName(),
+			State:    t.GetState(),
+			Status:   t.GetStatus(),
+			Progress: t.GetProgress(),
+		}
+		if err := t.GetErr(); err != nil {
+			info.Error = err.Error()
+		}
+		infos = append(infos, info)
+	}
+	return infos
+}
@@ -0,0 +1,89 @@
+package _115
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+const (
+	// throttleBackoff is the least time between two decreases, a burst of rejected requests counts once
+	throttleBackoff = time.Second
+	// throttleRecover is how long requests have to go through before the rate is raised a step
+	throttleRecover = 10 * time.Second
+	// throttleSteps is how many raises it takes to get from the lowest rate back to the highest
+	throttleSteps = 10
+	// minThrottleRate keeps a lowest rate of 0 from stopping every request
+	minThrottleRate = 0.1
+)
+
+// isRateLimited reports whether 115 turned a request down for coming too often,
+// webapi answers 405 from its firewall rather than 429
+func isRateLimited(status int) bool {
+	return status == http.StatusTooManyRequests || status == http.StatusMethodNotAllowed
+}
+
+// throttle adjusts the rate of limiter the AIMD way: halved when 115 answers
+// too many requests, raised by a fixed step once it has been quiet for a while
+type throttle struct {
+	mu        sync.Mutex
+	limiter   *rate.Limiter
+	lo, hi    float64
+	changedAt time.Time
+}
+
+// newThrottle starts at start clamped to [lo, hi], at hi when start is 0
+func newThrottle(start, lo, hi float64) *throttle {
+	lo = max(lo, minThrottleRate)
+	hi = max(hi, lo)
+	if start <= 0 {
+		start = hi
+	}
+	return &throttle{
+		limiter:   rate.NewLimiter(rate.Limit(min(max(start, lo), hi)), 1),
+		lo:        lo,
+		hi:        hi,
+		changedAt: time.Now(),
+	}
+}
+
+// limited halves the rate, down to lo
+func (t *throttle) limited(ctx context.Context) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if time.Since(t.changedAt) < throttleBackoff {
+		return
+	}
+	from := float64(t.limiter.Limit())
+	to := max(from/2, t.lo)
+	t.set(to)
+	if to != from {
+		logger(ctx).Warnf("115 is rate limiting, slowing down from %.2f to %.2f requests per second", from, to)
+	}
+}
+
+// ok raises the rate a step towards hi once no request was limited for throttleRecover
+func (t *throttle) ok() {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if time.Since(t.changedAt) < throttleRecover {
+		return
+	}
+	if from := float64(t.limiter.Limit()); from < t.hi {
+		t.set(min(from+(t.hi-t.lo)/throttleSteps, t.hi))
+	}
+}
+
+func (t *throttle) set(r float64) {
+	t.limiter.SetLimit(rate.Limit(r))
+	t.changedAt = time.Now()
+}
@@ -0,0 +1,66 @@
+package _115
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestThrottle(t *testing.T) {
+	limited := false
+	d := newTestDriver(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if limited {
+			http.Error(w, "too many requests", http.StatusTooManyRequests)
+			return
+		}
+		fakeTree{}.ServeHTTP(w, r)
+	}))
+	d.throttle = newThrottle(4, 1, 4)
+	d.limiter = d.throttle.limiter
+	rateNow := func() float64 { return float64(d.limiter.Limit()) }
+	// requests made after the throttle last changed the rate by more than d
+	requests := func(n int, after time.Duration) {
+		for i := 0; i < n; i++ {
+			d.throttle.changedAt = d.throttle.changedAt.Add(-after)
+			_, err := d.getFiles(context.Background(), "0")
+			if limited && !errors.Is(err, ErrRateLimited) {
+				t.Fatalf("expect %v, got %v", ErrRateLimited, err)
+			}
+		}
+	}
+
+	limited = true
+	requests(5, throttleBackoff)
+	if rateNow() != 1 {
+		t.Fatalf("expect the rate halved down to the lowest rate, got %.2f", rateNow())
+	}
+
+	d.throttle = newThrottle(4, 1, 4)
+	d.limiter = d.throttle.limiter
+	requests(1, throttleBackoff)
+	requests(5, 0)
+	if rateNow() != 2 {
+		t.Fatalf("expect a burst to halve the rate once, got %.2f", rateNow())
+	}
+	requests(1, throttleBackoff)
+	requests(1, throttleBackoff)
+	if rateNow() != 1 {
+		t.Fatalf("expect the rate to stop at the lowest rate, got %.2f", rateNow())
+	}
+
+	limited = false
+	requests(3, 0)
+	if rateNow() != 1 {
+		t.Fatalf("expect the rate kept until %v passed, got %.2f", throttleRecover, rateNow())
+	}
+	requests(1, throttleRecover)
+	if r := rateNow(); r < 1.29 || r > 1.31 {
+		t.Fatalf("expect the rate raised a step, got %.2f", rateNow())
+	}
+	requests(throttleSteps*2, throttleRecover)
+	if rateNow() != 4 {
+		t.Errorf("expect the rate to recover up to the highest rate, got %.2f", rateNow())
+	}
+}
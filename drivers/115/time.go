@@ -0,0 +1,63 @@
+package _115
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	driver115 "github.com/SheltonZhu/115driver/pkg/driver"
+)
+
+// cst is the timezone of 115 timestamps that don't carry one
+var cst = func() *time.Location {
+	loc, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		return time.FixedZone("CST", 8*3600)
+	}
+	return loc
+}()
+
+var timeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// parseTime reads a 115 timestamp, depending on the api it is epoch seconds,
+// epoch milliseconds or a formatted string in China Standard Time.
+// ok is false for empty or unknown values.
+func parseTime(s string) (t time.Time, ok bool) {
+	s = strings.Trim(strings.TrimSpace(s), `"`)
+	if s == "" || s == "0" {
+		return time.Time{}, false
+	}
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		// seconds won't reach 1e11 before the year 5000
+		if n >= 1e11 {
+			return time.UnixMilli(n), true
+		}
+		return time.Unix(n, 0), true
+	}
+	for _, layout := range timeLayouts {
+		if t, err := time.ParseInLocation(layout, s, cst); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// formatTime writes t the way the 115 apis take a timestamp, epoch seconds
+func formatTime(t time.Time) string {
+	return strconv.FormatInt(t.Unix(), 10)
+}
+
+// toFileObj converts a file of the 115 apis, all of them go through here
+// so their times are parsed the same way whatever endpoint they come from
+func toFileObj(info *driver115.FileInfo) FileObj {
+	var f driver115.File
+	f.From(info)
+	f.UpdateTime, _ = parseTime(info.UpdateTime)
+	f.CreateTime, _ = parseTime(strconv.FormatInt(int64(info.CreateTime), 10))
+	return FileObj{File: f}
+}
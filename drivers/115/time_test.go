@@ -0,0 +1,47 @@
+package _115
+
+import (
+	"testing"
+	"time"
+
+	driver115 "github.com/SheltonZhu/115driver/pkg/driver"
+)
+
+func TestParseTime(t *testing.T) {
+	want := time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC).Add(-8 * time.Hour)
+	for s, expect := range map[string]time.Time{
+		"1704179045":                want,
+		"1704179045000":             want,
+		`"1704179045"`:              want,
+		"2024-01-02 15:04:05":       want,
+		"2024-01-02 15:04":          want.Add(-5 * time.Second),
+		"2024-01-02":                want.Add(-15*time.Hour - 4*time.Minute - 5*time.Second),
+		"2024-01-02T07:04:05Z":      want,
+		"2024-01-02T15:04:05+08:00": want,
+	} {
+		got, ok := parseTime(s)
+		if !ok || !got.Equal(expect) {
+			t.Errorf("parse %s: expect %v, got %v (ok %v)", s, expect, got, ok)
+		}
+	}
+	for _, s := range []string{"", "0", "yesterday"} {
+		if got, ok := parseTime(s); ok {
+			t.Errorf("expect %q to be rejected, got %v", s, got)
+		}
+	}
+}
+
+func TestToFileObjTimes(t *testing.T) {
+	want := time.Date(2024, 1, 2, 7, 4, 0, 0, time.UTC)
+	for _, info := range []driver115.FileInfo{
+		{FileID: "1", UpdateTime: "2024-01-02 15:04", CreateTime: 1704179040},
+		{FileID: "1", UpdateTime: "1704179040", CreateTime: 1704179040000},
+		{CategoryID: "2", UpdateTime: "1704179040", CreateTime: 1704179040},
+		{CategoryID: "2", UpdateTime: "2024-01-02 15:04", CreateTime: 1704179040},
+	} {
+		f := toFileObj(&info)
+		if !f.ModTime().Equal(want) || !f.CreateTime().Equal(want) {
+			t.Errorf("%+v: expect %v, got mtime %v ctime %v", info, want, f.ModTime(), f.CreateTime())
+		}
+	}
+}
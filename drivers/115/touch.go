@@ -0,0 +1,53 @@
+package _115
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	driver115 "github.com/SheltonZhu/115driver/pkg/driver"
+	"github.com/alist-org/alist/v3/internal/errs"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/pkg/errors"
+)
+
+const apiFileEdit = "https://webapi.115.com/files/edit"
+
+type TouchResult struct {
+	FileID  string    `json:"file_id"`
+	ModTime time.Time `json:"mod_time"`
+}
+
+// Touch sets the modification time of obj to t, leaving its content alone. The file is
+// looked up again after, an api that doesn't take the time or ignores it fails with errs.NotSupport.
+func (d *Pan115) Touch(ctx context.Context, obj model.Obj, t time.Time) (*TouchResult, error) {
+	if err := d.WaitLimit(ctx); err != nil {
+		return nil, err
+	}
+	fileID := obj.GetID()
+	result := driver115.BasicResp{}
+	resp, err := d.client.Load().Client.R().
+		SetContext(ctx).
+		SetFormData(map[string]string{"fid": fileID, "user_utime": formatTime(t)}).
+		SetResult(&result).
+		ForceContentType("application/json;charset=UTF-8").
+		Post(apiFileEdit)
+	if resp != nil && (resp.StatusCode() == http.StatusNotFound || resp.StatusCode() == http.StatusNotImplemented) {
+		return nil, errors.Wrapf(errs.NotSupport, "touch answered %s", resp.Status())
+	}
+	if err = driver115.CheckErr(err, &result, resp); err != nil {
+		return nil, err
+	}
+	if err := d.WaitLimit(ctx); err != nil {
+		return nil, err
+	}
+	f, err := d.getNewFile(ctx, fileID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "get info of %s", fileID)
+	}
+	if f.ModTime().Unix() != t.Unix() {
+		return nil, errors.Wrapf(errs.NotSupport, "115 kept the modified time %s of %s", f.ModTime(), f.GetName())
+	}
+	logger(ctx).Debugf("set the modified time of %s to %s", f.GetName(), t)
+	return &TouchResult{FileID: fileID, ModTime: f.ModTime()}, nil
+}
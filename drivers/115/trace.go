@@ -0,0 +1,49 @@
+package _115
+
+import (
+	"context"
+
+	"github.com/alist-org/alist/v3/pkg/utils/random"
+	log "github.com/sirupsen/logrus"
+)
+
+type reqIDKey struct{}
+
+// withReqID makes sure ctx carries a short correlation id,
+// sub-calls and retries of the same operation share it in their log lines
+func withReqID(ctx context.Context) context.Context {
+	if reqID(ctx) != "" {
+		return ctx
+	}
+	return context.WithValue(ctx, reqIDKey{}, random.String(8))
+}
+
+func reqID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(reqIDKey{}).(string)
+	return id
+}
+
+func logger(ctx context.Context) *log.Entry {
+	entry := log.WithField("driver", "115")
+	if id := reqID(ctx); id != "" {
+		entry = entry.WithField("req_id", id)
+	}
+	return entry
+}
+
+// retry runs fn up to attempts times, every failed attempt is logged with the req id of ctx
+func retry(ctx context.Context, attempts int, what string, fn func() error) (err error) {
+	for i := 1; i <= attempts; i++ {
+		if err = fn(); err == nil {
+			return nil
+		}
+		logger(ctx).Debugf("%s failed (attempt %d/%d): %v", what, i, attempts, err)
+		if isNoRetry(err) {
+			return err
+		}
+	}
+	return err
+}
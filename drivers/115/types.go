@@ -1,6 +1,8 @@
 package _115
 
 import (
+	"context"
+	"fmt"
 	"time"
 
 	"github.com/SheltonZhu/115driver/pkg/driver"
@@ -12,12 +14,24 @@ var _ model.Obj = (*FileObj)(nil)
 
 type FileObj struct {
 	driver.File
+	// UnderReview is set while 115 reviews the content of the file, it can't be downloaded until it passes
+	UnderReview bool
 }
 
 func (f *FileObj) CreateTime() time.Time {
 	return f.File.CreateTime
 }
 
+// ETag is built from the file id, which survives renames and moves, and the modification time,
+// which a content update bumps. Files add their size, folders leave it out as it is only known
+// with ShowFolderSize and would change the ETag along with the option.
+func (f *FileObj) ETag(ctx context.Context) (string, error) {
+	if f.IsDir() {
+		return fmt.Sprintf(`"115-%s-%x"`, f.GetID(), f.ModTime().Unix()), nil
+	}
+	return fmt.Sprintf(`"115-%s-%x-%x"`, f.GetID(), f.ModTime().Unix(), f.GetSize()), nil
+}
+
 func (f *FileObj) GetHash() utils.HashInfo {
 	return utils.NewHashInfo(utils.SHA1, f.Sha1)
 }
@@ -0,0 +1,154 @@
+package _115
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	driver115 "github.com/SheltonZhu/115driver/pkg/driver"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/internal/op"
+	"github.com/alist-org/alist/v3/internal/stream"
+	"github.com/alist-org/alist/v3/internal/task"
+	"github.com/alist-org/alist/v3/pkg/utils"
+	"github.com/xhofe/tache"
+)
+
+// UploadTask uploads a file to oss in the background after rapid upload missed,
+// it owns a copy of the file since the original stream is closed once Put returns.
+// The copy is kept until the task succeeds, is canceled or runs out of retries.
+type UploadTask struct {
+	task.TaskExtension
+	Status    string                    `json:"-"`
+	StorageMp string                    `json:"storage_mp"`
+	DirID     string                    `json:"dir_id"`
+	DirPath   string                    `json:"dir_path"`
+	Name      string                    `json:"name"`
+	Size      int64                     `json:"size"`
+	FilePath  string                    `json:"file_path"`
+	SHA1      string                    `json:"sha1"`
+	Params    driver115.UploadOSSParams `json:"params"`
+	d         *Pan115
+}
+
+var (
+	uploadsOnce sync.Once
+	uploads     *tache.Manager[*UploadTask]
+)
+
+// uploadManager runs the uploads queued by queue_upload_on_miss. It belongs to the driver,
+// the tasks keep a pointer to their storage, so they aren't persisted.
+func uploadManager() *tache.Manager[*UploadTask] {
+	uploadsOnce.Do(func() {
+		uploads = tache.NewManager[*UploadTask](tache.WithWorks(2), tache.WithMaxRetry(2))
+	})
+	return uploads
+}
+
+func (t *UploadTask) GetName() string {
+	return fmt.Sprintf("upload %s to [%s](%s)", t.Name, t.StorageMp, t.DirID)
+}
+
+func (t *UploadTask) GetStatus() string {
+	return t.Status
+}
+
+func (t *UploadTask) Run() (err error) {
+	t.ReinitCtx()
+	t.ClearEndTime()
+	t.SetStartTime(time.Now())
+	t.SetTotalBytes(t.Size)
+	defer func() {
+		t.SetEndTime(time.Now())
+		if err == nil || utils.IsCanceled(t.Ctx()) {
+			_ = os.Remove(t.FilePath)
+		}
+	}()
+	file, err := os.Open(t.FilePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	t.Status = "uploading"
+	ctx := withReqID(t.Ctx())
+	s := &stream.FileStream{
+		Ctx:    ctx,
+		Obj:    &model.Object{Name: t.Name, Size: t.Size, Modified: time.Now()},
+		Reader: file,
+	}
+	params := t.Params
+	params.SHA1 = t.SHA1
+	if _, err = t.d.uploadByOSS(ctx, &params, s, t.DirID, t.SetProgress); err != nil {
+		return err
+	}
+	t.d.views.Del(t.DirID)
+	op.ClearCache(t.d, t.DirPath)
+	t.Status = "uploaded"
+	return nil
+}
+
+func (t *UploadTask) OnFailed() {
+	_ = os.Remove(t.FilePath)
+}
+
+// queuedUpload is the task still uploading name of size to dirID, if any
+func (d *Pan115) queuedUpload(dirID, name string, size int64) *UploadTask {
+	tasks := uploadManager().GetByCondition(func(t *UploadTask) bool {
+		return t.d == d && t.DirID == dirID && t.Name == name && t.Size == size &&
+			utils.SliceContains([]tache.State{tache.StatePending, tache.StateRunning, tache.StateWaitingRetry, tache.StateBeforeRetry}, t.GetState())
+	})
+	if len(tasks) == 0 {
+		return nil
+	}
+	return tasks[0]
+}
+
+// queueUpload hands the oss upload of s to a background task, a file already queued isn't queued again
+func (d *Pan115) queueUpload(ctx context.Context, params *driver115.UploadOSSParams, s model.FileStreamer, dstDir model.Obj) (*UploadTask, error) {
+	dirID := dstDir.GetID()
+	if t := d.queuedUpload(dirID, s.GetName(), s.GetSize()); t != nil {
+		return t, nil
+	}
+	tmpF, err := s.CacheFullInTempFile()
+	if err != nil {
+		return nil, err
+	}
+	file, err := utils.CreateTempFile(io.NewSectionReader(tmpF, 0, s.GetSize()), s.GetSize())
+	if err != nil {
+		return nil, err
+	}
+	_ = file.Close()
+	taskCreator, _ := ctx.Value("user").(*model.User)
+	t := &UploadTask{
+		TaskExtension: task.TaskExtension{
+			Creator: taskCreator,
+		},
+		Status:    "queued",
+		StorageMp: d.MountPath,
+		DirID:     dirID,
+		DirPath:   dstDir.GetPath(),
+		Name:      s.GetName(),
+		Size:      s.GetSize(),
+		FilePath:  file.Name(),
+		SHA1:      params.SHA1,
+		Params:    *params,
+		d:         d,
+	}
+	t.SetTotalBytes(s.GetSize())
+	uploadManager().Add(t)
+	logger(ctx).Infof("rapid upload of %s missed, queued as task %s", s.GetName(), t.GetID())
+	return t, nil
+}
+
+// UploadTasks lists the queued uploads of the storage
+func (d *Pan115) UploadTasks() []TaskInfo {
+	return taskInfos(uploadManager().GetByCondition(func(t *UploadTask) bool { return t.d == d }))
+}
+
+// cancelUploads stops the queued uploads of the storage when it's dropped
+func (d *Pan115) cancelUploads() {
+	uploadManager().CancelByCondition(func(t *UploadTask) bool { return t.d == d })
+}
@@ -0,0 +1,35 @@
+package _115
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alist-org/alist/v3/pkg/utils"
+)
+
+func TestSplitFilePartLimit(t *testing.T) {
+	d := &Pan115{Addition: Addition{UploadPartSize: 10}}
+	check := func(fileSize, partSize int64, parts int) {
+		chunks, err := d.splitFile(context.Background(), fileSize)
+		if err != nil {
+			t.Fatalf("split %d bytes failed: %v", fileSize, err)
+		}
+		if len(chunks) != parts || chunks[0].Size != partSize {
+			t.Errorf("expect %d parts of %d bytes for %d bytes, got %d of %d", parts, partSize, fileSize, len(chunks), chunks[0].Size)
+		}
+		last := chunks[len(chunks)-1]
+		if last.Offset+last.Size != fileSize {
+			t.Errorf("expect the parts to cover %d bytes, got %d", fileSize, last.Offset+last.Size)
+		}
+	}
+	check(1*utils.GB, 10*utils.MB, 103)
+	// 10 MB parts would make 20480 parts
+	check(200*utils.GB, (200*utils.GB+maxOSSParts-1)/maxOSSParts, 10000)
+
+	if size, err := fitPartSize(10*utils.MB, utils.KB); err != nil || size != minOSSPartSize {
+		t.Errorf("expect parts raised to the oss minimum, got %d %v", size, err)
+	}
+	if _, err := fitPartSize(maxOSSParts*maxOSSPartSize+1, utils.MB); err == nil {
+		t.Error("expect a file too large for oss to fail")
+	}
+}
@@ -9,8 +9,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"maps"
 	"net/http"
 	"net/url"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
@@ -20,18 +22,29 @@ import (
 	"github.com/alist-org/alist/v3/internal/conf"
 	"github.com/alist-org/alist/v3/internal/driver"
 	"github.com/alist-org/alist/v3/internal/model"
-	"github.com/alist-org/alist/v3/pkg/http_range"
 	"github.com/alist-org/alist/v3/pkg/utils"
 	"github.com/aliyun/aliyun-oss-go-sdk/oss"
 
 	cipher "github.com/SheltonZhu/115driver/pkg/crypto/ec115"
 	crypto "github.com/SheltonZhu/115driver/pkg/crypto/m115"
 	driver115 "github.com/SheltonZhu/115driver/pkg/driver"
+	"github.com/Xhofe/go-cache"
 	"github.com/pkg/errors"
 )
 
 // var UserAgent = driver115.UA115Browser
+
+// login builds a new client from the credentials and swaps it in, calls already
+// running keep the client they started with
 func (d *Pan115) login() error {
+	client, err := d.newClient()
+	if client != nil && (err == nil || d.client.Load() == nil) {
+		d.client.Store(client)
+	}
+	return err
+}
+
+func (d *Pan115) newClient() (*driver115.Pan115Client, error) {
 	var err error
 	opts := []driver115.Option{
 		driver115.UA(d.getUA()),
@@ -39,54 +52,320 @@ func (d *Pan115) login() error {
 			c.Client.SetTLSClientConfig(&tls.Config{InsecureSkipVerify: conf.Conf.TlsInsecureSkipVerify})
 		},
 	}
-	d.client = driver115.New(opts...)
+	if d.transport != nil {
+		opts = append(opts, func(c *driver115.Pan115Client) { c.Client.SetTransport(d.transport) })
+	}
+	client := driver115.New(opts...)
+	if err = applyIPFamily(client.Client, d.IPFamily); err != nil {
+		return nil, err
+	}
+	applyAPIDomain(client, d.domain)
+	d.hookClient(client)
 	cr := &driver115.Credential{}
 	if d.QRCodeToken != "" {
+		d.masker.add(d.QRCodeToken)
 		s := &driver115.QRCodeSession{
 			UID: d.QRCodeToken,
 		}
-		if cr, err = d.client.QRCodeLoginWithApp(s, driver115.LoginApp(d.QRCodeSource)); err != nil {
-			return errors.Wrap(err, "failed to login by qrcode")
+		if cr, err = client.QRCodeLoginWithApp(s, driver115.LoginApp(d.QRCodeSource)); err != nil {
+			return client, errors.Wrap(err, "failed to login by qrcode")
 		}
+		d.masker.add(cr.UID, cr.CID, cr.SEID, cr.KID)
+		importDomainCookies(client, cr, d.domain)
 		d.Cookie = fmt.Sprintf("UID=%s;CID=%s;SEID=%s;KID=%s", cr.UID, cr.CID, cr.SEID, cr.KID)
 		d.QRCodeToken = ""
 	} else if d.Cookie != "" {
-		if err = cr.FromCookie(d.Cookie); err != nil {
-			return errors.Wrap(err, "failed to login by cookies")
+		c, err := parseCookie(d.Cookie, time.Now())
+		if c != nil {
+			d.masker.add(c.cr.UID, c.cr.CID, c.cr.SEID, c.cr.KID)
 		}
-		d.client.ImportCredential(cr)
+		if err != nil {
+			return client, errors.Wrap(err, "failed to login by cookies")
+		}
+		if len(c.extras) > 0 {
+			logger(context.Background()).Debugf("ignoring cookie fields %s", strings.Join(c.extras, ", "))
+		}
+		if len(c.stale) > 0 {
+			if d.StrictCookie {
+				return client, errors.Wrap(ErrStaleCookie, strings.Join(c.stale, ", "))
+			}
+			logger(context.Background()).Warnf("the cookie looks stale, export it again if logging in fails: %s", strings.Join(c.stale, ", "))
+		}
+		client.ImportCredential(&c.cr)
+		importDomainCookies(client, &c.cr, d.domain)
 	} else {
-		return errors.New("missing cookie or qrcode account")
+		return client, errors.New("missing cookie or qrcode account")
 	}
-	return d.client.LoginCheck()
+	return client, client.LoginCheck()
 }
 
-func (d *Pan115) getFiles(fileId string) ([]FileObj, error) {
+func (d *Pan115) getFiles(ctx context.Context, fileId string) ([]FileObj, error) {
+	return d.listFiles(ctx, fileId, nil)
+}
+
+// listFiles lists every page of fileId, query is added to the parameters of each page
+func (d *Pan115) listFiles(ctx context.Context, fileId string, query map[string]string) ([]FileObj, error) {
 	res := make([]FileObj, 0)
-	if d.PageSize <= 0 {
-		d.PageSize = driver115.FileListLimit
+	limit := d.PageSize
+	if limit <= 0 {
+		limit = driver115.FileListLimit
 	}
-	files, err := d.client.ListWithLimit(fileId, d.PageSize, driver115.WithMultiUrls())
-	if err != nil {
+	limit = min(limit, driver115.MaxDirPageLimit)
+	apiURLs := []string{driver115.ApiFileList, driver115.ApiFileList1, driver115.ApiFileList2, driver115.ApiFileList3}
+	skipped := 0
+	order := d.defaultOrder()
+	if d.FolderSort {
+		// the first page is left to the sort of the folder, the others follow what 115 reports for it
+		order = nil
+	}
+	// a listing of the folder with its ETag, for 115 to answer not modified to
+	var (
+		cached listing
+		etag   string
+	)
+	if query == nil {
+		cached, _ = d.listings.Get(fileId)
+	}
+	for i, offset := 0, int64(0); ; i++ {
+		params := maps.Clone(query)
+		if params == nil {
+			params = map[string]string{}
+		}
+		maps.Copy(params, order)
+		ifNoneMatch := ""
+		if i == 0 && cached.etag != "" {
+			ifNoneMatch = cached.etag
+		}
+		result, err := d.listPage(ctx, apiURLs[i%len(apiURLs)], fileId, offset, limit, params, ifNoneMatch)
+		if err != nil {
+			return nil, err
+		}
+		if result.NotModified {
+			return slices.Clone(cached.files), nil
+		}
+		if offset == 0 {
+			etag = result.ETag
+		}
+		if order == nil {
+			if result.Order == "" {
+				// no sort of its own, start over in the default one
+				order = d.defaultOrder()
+				continue
+			}
+			order = map[string]string{"o": result.Order, "asc": strconv.Itoa(result.IsAsc)}
+		}
+		for j := range result.Files {
+			f := toFileObj(&result.Files[j].FileInfo)
+			f.UnderReview = result.Files[j].Audit == 1
+			res = append(res, f)
+		}
+		skipped += result.Skipped
+		offset = int64(result.Offset) + limit
+		if offset >= int64(result.Count) {
+			if skipped > 0 {
+				logger(ctx).Warnf("listed folder %s without %d malformed entries", fileId, skipped)
+			}
+			if query == nil && etag != "" && d.CacheExpiration > 0 {
+				d.listings.Set(fileId, listing{etag: etag, files: slices.Clone(res)},
+					cache.WithEx[listing](time.Minute*time.Duration(d.CacheExpiration)))
+			}
+			return res, nil
+		}
+	}
+}
+
+// listing is a folder as listed along with the ETag 115 sent for it
+type listing struct {
+	etag  string
+	files []FileObj
+}
+
+// defaultOrder is the sort of listings set by OrderBy and OrderDirection
+func (d *Pan115) defaultOrder() map[string]string {
+	order, asc := d.OrderBy, "1"
+	if order == "" {
+		order = driver115.FileOrderByTime
+	}
+	if d.OrderDirection == "desc" {
+		asc = "0"
+	}
+	return map[string]string{"o": order, "asc": asc}
+}
+
+// fileListResp is driver115.FileListResp with the fields the library drops
+type fileListResp struct {
+	driver115.FileListResp
+	Files []listEntry `json:"data"`
+	// Skipped counts the entries left out for being malformed
+	Skipped int `json:"-"`
+	// ETag is the version of the listing, when 115 sent one
+	ETag string `json:"-"`
+	// NotModified is a listing unchanged since the ETag it was asked with
+	NotModified bool `json:"-"`
+}
+
+// rawListResp leaves the entries of a listing to be decoded one by one
+type rawListResp struct {
+	driver115.FileListResp
+	Files []json.RawMessage `json:"data"`
+}
+
+type listEntry struct {
+	driver115.FileInfo
+	// Audit is 1 while 115 holds the file back for content review
+	Audit driver115.StringInt `json:"audit"`
+}
+
+// listPage gets one page of fileId, with the parameters driver115.GetFiles uses but the sort,
+// which query sets. With ifNoneMatch the page is only sent when the listing changed since.
+func (d *Pan115) listPage(ctx context.Context, apiURL, fileId string, offset, limit int64, query map[string]string, ifNoneMatch string) (*fileListResp, error) {
+	if fileId == "" {
+		fileId = "0"
+	}
+	raw := rawListResp{}
+	req := d.client.Load().Client.R().SetContext(ctx)
+	if ifNoneMatch != "" {
+		req.SetHeader("If-None-Match", ifNoneMatch)
+	}
+	resp, err := req.
+		SetQueryParams(map[string]string{
+			"aid":              "1",
+			"cid":              fileId,
+			"offset":           strconv.FormatInt(offset, 10),
+			"show_dir":         "1",
+			"limit":            strconv.FormatInt(limit, 10),
+			"snap":             "0",
+			"natsort":          "0",
+			"record_open_time": "1",
+			"format":           "json",
+			"fc_mix":           "0",
+		}).
+		SetQueryParams(query).
+		SetResult(&raw).
+		ForceContentType("application/json;charset=UTF-8").
+		Get(apiURL)
+	if err == nil && ifNoneMatch != "" && resp.StatusCode() == http.StatusNotModified {
+		return &fileListResp{NotModified: true}, nil
+	}
+	if err == nil {
+		if err := d.checkCodes(ctx, resp.Body(), blockCodes); err != nil {
+			return nil, err
+		}
+	}
+	if err = driver115.CheckErr(err, &raw, resp); err != nil {
 		return nil, err
 	}
-	for _, file := range *files {
-		res = append(res, FileObj{file})
+	result := &fileListResp{FileListResp: raw.FileListResp, Files: make([]listEntry, 0, len(raw.Files)), ETag: resp.Header().Get("ETag")}
+	for i, data := range raw.Files {
+		var entry listEntry
+		err := json.Unmarshal(data, &entry)
+		if err == nil && entry.Name == "" {
+			err = errors.New("entry has no name")
+		}
+		if err != nil {
+			if d.StrictListing {
+				return nil, errors.Wrapf(err, "entry %d of folder %s", raw.Offset+i, fileId)
+			}
+			logger(ctx).Warnf("leaving out malformed entry %d of folder %s: %v", raw.Offset+i, fileId, err)
+			result.Skipped++
+			continue
+		}
+		result.Files = append(result.Files, entry)
 	}
-	return res, nil
+	return result, nil
 }
 
-func (d *Pan115) getNewFile(fileId string) (*FileObj, error) {
-	file, err := d.client.GetFile(fileId)
-	if err != nil {
+func (d *Pan115) getNewFile(ctx context.Context, fileId string) (*FileObj, error) {
+	result := driver115.GetFileInfoResponse{}
+	req := d.client.Load().Client.R().
+		SetContext(ctx).
+		SetQueryParam("file_id", fileId).
+		ForceContentType("application/json;charset=UTF-8").
+		SetResult(&result)
+	resp, err := req.Get(driver115.ApiFileInfo)
+	if err := driver115.CheckErr(err, &result, resp); err != nil {
 		return nil, err
 	}
-	return &FileObj{*file}, nil
+	fileInfo := &driver115.FileInfo{}
+	if len(result.Files) > 0 {
+		fileInfo = result.Files[0]
+	}
+	f := toFileObj(fileInfo)
+	return &f, nil
+}
+
+// checkUploadDir makes sure dirID is backed by a real 115 folder,
+// virtual folders resolve to an empty cid and can't hold uploads.
+func (d *Pan115) checkUploadDir(ctx context.Context, dirID string) error {
+	if dirID == "0" {
+		return nil
+	}
+	dir, err := d.getNewFile(ctx, dirID)
+	if err != nil {
+		return errors.Wrapf(ErrUploadDirNotWritable, "%s: %v", dirID, err)
+	}
+	if !dir.IsDirectory || dir.FileID != dirID {
+		return errors.Wrap(ErrUploadDirNotWritable, dirID)
+	}
+	return nil
 }
 
-func (d *Pan115) getNewFileByPickCode(pickCode string) (*FileObj, error) {
+// confirmDeleted looks obj up again, a delete that 115 acknowledged
+// but didn't carry out fails with ErrDeleteNotConfirmed
+func (d *Pan115) confirmDeleted(ctx context.Context, obj model.Obj) error {
+	if err := d.WaitLimit(ctx); err != nil {
+		return err
+	}
+	f, err := d.getNewFile(ctx, obj.GetID())
+	if errors.Is(err, driver115.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return errors.Wrapf(err, "confirm delete of %s", obj.GetName())
+	}
+	if f.GetID() == obj.GetID() {
+		logger(ctx).Warnf("%s still exists after deleting it", obj.GetName())
+		return errors.Wrap(ErrDeleteNotConfirmed, obj.GetName())
+	}
+	return nil
+}
+
+// confirmUploaded lists dirID again for the file an upload of s added, file when 115 told
+// which one it is. A file that isn't there, or is there with another size or sha1,
+// fails with ErrUploadNotVisible.
+func (d *Pan115) confirmUploaded(ctx context.Context, dirID string, s model.FileStreamer, sha1 string, file *FileObj) error {
+	if err := d.WaitLimit(ctx); err != nil {
+		return err
+	}
+	files, err := d.getFiles(ctx, dirID)
+	if err != nil {
+		return errors.Wrapf(err, "confirm upload of %s", s.GetName())
+	}
+	name := d.normName(s.GetName())
+	for _, f := range files {
+		if file != nil && file.GetID() != "" {
+			if f.GetID() != file.GetID() {
+				continue
+			}
+		} else if f.GetName() != name {
+			continue
+		}
+		if f.GetSize() != s.GetSize() || !strings.EqualFold(f.Sha1, sha1) {
+			logger(ctx).Warnf("%s shows up after upload as %d bytes with sha1 %s, expected %d bytes with %s",
+				s.GetName(), f.GetSize(), f.Sha1, s.GetSize(), sha1)
+			return errors.Wrapf(ErrUploadNotVisible, "%s has another size or sha1", s.GetName())
+		}
+		d.views.Del(dirID)
+		return nil
+	}
+	logger(ctx).Warnf("%s doesn't show up in its folder after upload", s.GetName())
+	return errors.Wrap(ErrUploadNotVisible, s.GetName())
+}
+
+func (d *Pan115) getNewFileByPickCode(ctx context.Context, pickCode string) (*FileObj, error) {
 	result := driver115.GetFileInfoResponse{}
-	req := d.client.NewRequest().
+	req := d.client.Load().Client.R().
+		SetContext(ctx).
 		SetQueryParam("pick_code", pickCode).
 		ForceContentType("application/json;charset=UTF-8").
 		SetResult(&result)
@@ -97,18 +376,15 @@ func (d *Pan115) getNewFileByPickCode(pickCode string) (*FileObj, error) {
 	if len(result.Files) == 0 {
 		return nil, errors.New("not get file info")
 	}
-	fileInfo := result.Files[0]
-
-	f := &FileObj{}
-	f.From(fileInfo)
-	return f, nil
+	f := toFileObj(result.Files[0])
+	return &f, nil
 }
 
 func (d *Pan115) getUA() string {
-	return fmt.Sprintf("Mozilla/5.0 115Browser/%s", appVer)
+	return fmt.Sprintf("Mozilla/5.0 115Browser/%s", d.appVersion())
 }
 
-func (d *Pan115) DownloadWithUA(pickCode, ua string) (*driver115.DownloadInfo, error) {
+func (d *Pan115) DownloadWithUA(ctx context.Context, pickCode, ua string) (*driver115.DownloadInfo, error) {
 	key := crypto.GenerateKey()
 	result := driver115.DownloadResp{}
 	params, err := utils.Json.Marshal(map[string]string{"pick_code": pickCode})
@@ -119,17 +395,25 @@ func (d *Pan115) DownloadWithUA(pickCode, ua string) (*driver115.DownloadInfo, e
 	data := crypto.Encode(params, key)
 
 	bodyReader := strings.NewReader(url.Values{"data": []string{data}}.Encode())
-	reqUrl := fmt.Sprintf("%s?t=%s", driver115.AndroidApiDownloadGetUrl, driver115.Now().String())
-	req, _ := http.NewRequest(http.MethodPost, reqUrl, bodyReader)
+	// a plain http request, the api domain hook of the resty client doesn't see it
+	reqUrl := apiURL(fmt.Sprintf("%s?t=%s", driver115.AndroidApiDownloadGetUrl, driver115.Now().String()), d.domain)
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, reqUrl, bodyReader)
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	req.Header.Set("Cookie", d.Cookie)
 	req.Header.Set("User-Agent", ua)
+	if d.DownloadReferer != "" {
+		// the request headers end up in the link, so the cdn gets the referer too
+		req.Header.Set("Referer", d.DownloadReferer)
+	}
 
-	resp, err := d.client.Client.GetClient().Do(req)
+	resp, err := d.client.Load().Client.GetClient().Do(req)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
+	if err = checkLoginRedirect(resp); err != nil {
+		return nil, err
+	}
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
@@ -139,6 +423,9 @@ func (d *Pan115) DownloadWithUA(pickCode, ua string) (*driver115.DownloadInfo, e
 		return nil, err
 	}
 
+	if err := d.checkCodes(ctx, body, downloadCodes, blockCodes); err != nil {
+		return nil, err
+	}
 	if err = result.Err(string(body)); err != nil {
 		return nil, err
 	}
@@ -163,13 +450,27 @@ func (d *Pan115) DownloadWithUA(pickCode, ua string) (*driver115.DownloadInfo, e
 }
 
 func (c *Pan115) GenerateToken(fileID, preID, timeStamp, fileSize, signKey, signVal string) string {
-	userID := strconv.FormatInt(c.client.UserID, 10)
+	userID := strconv.FormatInt(c.client.Load().UserID, 10)
 	userIDMd5 := md5.Sum([]byte(userID))
-	tokenMd5 := md5.Sum([]byte(md5Salt + fileID + fileSize + signKey + signVal + userID + timeStamp + hex.EncodeToString(userIDMd5[:]) + appVer))
+	tokenMd5 := md5.Sum([]byte(md5Salt + fileID + fileSize + signKey + signVal + userID + timeStamp + hex.EncodeToString(userIDMd5[:]) + c.appVersion()))
 	return hex.EncodeToString(tokenMd5[:])
 }
 
-func (d *Pan115) rapidUpload(fileSize int64, fileName, dirID, preID, fileID string, stream model.FileStreamer) (*driver115.UploadInitResp, error) {
+// rapidUploadForm is the form rapidUpload sends before the time, token and sign fields are added
+func (d *Pan115) rapidUploadForm(fileSize, fileName, target, fileID string) url.Values {
+	form := url.Values{}
+	form.Set("appid", "0")
+	form.Set("appversion", d.appVersion())
+	form.Set("userid", strconv.FormatInt(d.client.Load().UserID, 10))
+	form.Set("filename", fileName)
+	form.Set("filesize", fileSize)
+	form.Set("fileid", fileID)
+	form.Set("target", target)
+	form.Set("sig", d.client.Load().GenerateSignature(fileID, target))
+	return form
+}
+
+func (d *Pan115) rapidUpload(ctx context.Context, fileSize int64, fileName, dirID, preID, fileID string, stream model.FileStreamer) (*driver115.UploadInitResp, error) {
 	var (
 		ecdhCipher   *cipher.EcdhCipher
 		encrypted    []byte
@@ -185,18 +486,9 @@ func (d *Pan115) rapidUpload(fileSize int64, fileName, dirID, preID, fileID stri
 		return nil, err
 	}
 
-	userID := strconv.FormatInt(d.client.UserID, 10)
-	form := url.Values{}
-	form.Set("appid", "0")
-	form.Set("appversion", appVer)
-	form.Set("userid", userID)
-	form.Set("filename", fileName)
-	form.Set("filesize", fileSizeStr)
-	form.Set("fileid", fileID)
-	form.Set("target", target)
-	form.Set("sig", d.client.GenerateSignature(fileID, target))
+	form := d.rapidUploadForm(fileSizeStr, fileName, target, fileID)
 
-	signKey, signVal := "", ""
+	signKey, signVal, signCheck := "", "", ""
 	for retry := true; retry; {
 		t := driver115.NowMilli()
 
@@ -218,7 +510,8 @@ func (d *Pan115) rapidUpload(fileSize int64, fileName, dirID, preID, fileID stri
 			return nil, err
 		}
 
-		req := d.client.NewRequest().
+		req := d.client.Load().Client.R().
+			SetContext(ctx).
 			SetQueryParams(params).
 			SetBody(encrypted).
 			SetHeaderVerbatim("Content-Type", "application/x-www-form-urlencoded").
@@ -235,13 +528,29 @@ func (d *Pan115) rapidUpload(fileSize int64, fileName, dirID, preID, fileID stri
 		if decrypted, err = ecdhCipher.Decrypt(bodyBytes); err != nil {
 			return nil, err
 		}
+		if err = d.checkCodes(ctx, decrypted, uploadInitCodes, blockCodes); err != nil {
+			return nil, err
+		}
 		if err = driver115.CheckErr(json.Unmarshal(decrypted, &result), &result, resp); err != nil {
+			if signKey != "" {
+				return nil, errors.Wrapf(ErrRapidVerifyFailed, "range %s: %v", signCheck, err)
+			}
 			return nil, err
 		}
+		if result.Status == 7 && signKey != "" {
+			return nil, errors.Wrapf(ErrRapidVerifyFailed, "range %s, asked for range %s next", signCheck, result.SignCheck)
+		}
 		if result.Status == 7 {
+			logger(ctx).Debugf("rapid upload of %s asks for sign check of range %s, retrying", fileName, result.SignCheck)
 			// Update signKey & signVal
-			signKey = result.SignKey
+			signKey, signCheck = result.SignKey, result.SignCheck
 			signVal, err = UploadDigestRange(stream, result.SignCheck)
+			if errors.Is(err, ErrRangeUnavailable) && result.Bucket != "" && result.Object != "" {
+				// the answer carries what the oss upload needs, send the file instead
+				logger(ctx).Warnf("rapid upload of %s can't be checked, uploading it: %v", fileName, err)
+				result.Status, result.SHA1 = 1, fileID
+				break
+			}
 			if err != nil {
 				return nil, err
 			}
@@ -254,32 +563,84 @@ func (d *Pan115) rapidUpload(fileSize int64, fileName, dirID, preID, fileID stri
 	return &result, nil
 }
 
-func UploadDigestRange(stream model.FileStreamer, rangeSpec string) (result string, err error) {
-	var start, end int64
-	if _, err = fmt.Sscanf(rangeSpec, "%d-%d", &start, &end); err != nil {
-		return
+// retryRapidVerify starts the upload over once when 115 rejected the range hash of init, which happens
+// when the range was read wrong. The stream is cached in a temp file first so the range is read from disk.
+func (d *Pan115) retryRapidVerify(ctx context.Context, stream model.FileStreamer, init func() (*driver115.UploadInitResp, error)) (*driver115.UploadInitResp, error) {
+	res, err := init()
+	if !errors.Is(err, ErrRapidVerifyFailed) || !d.RetryRapidVerify {
+		return res, err
 	}
+	logger(ctx).Warnf("rapid upload check of %s failed, starting the upload over: %v", stream.GetName(), err)
+	if _, err := stream.CacheFullInTempFile(); err != nil {
+		return nil, err
+	}
+	return init()
+}
 
-	length := end - start + 1
-	reader, err := stream.RangeRead(http_range.Range{Start: start, Length: length})
-	if err != nil {
-		return "", err
+const (
+	// uploadInProgressRetries is how often an upload waits for the same upload of another session
+	uploadInProgressRetries = 5
+	uploadWaitPolicy        = "wait"
+)
+
+// waitUploadInProgress calls init again after DuplicateUploadWait seconds while 115 is receiving
+// the same file from another session, so the upload can finish as a rapid upload once that one is
+// done. With DuplicateUpload set to fail ErrUploadInProgress is returned as is.
+func (d *Pan115) waitUploadInProgress(ctx context.Context, name string, init func() (*driver115.UploadInitResp, error)) (*driver115.UploadInitResp, error) {
+	for attempt := 1; ; attempt++ {
+		res, err := init()
+		if !errors.Is(err, ErrUploadInProgress) || d.DuplicateUpload != uploadWaitPolicy || attempt > uploadInProgressRetries {
+			return res, err
+		}
+		wait := time.Duration(d.DuplicateUploadWait) * time.Second
+		logger(ctx).Infof("%s is being uploaded by another session, checking again in %s (%d/%d)", name, wait, attempt, uploadInProgressRetries)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
 	}
-	hashStr, err := utils.HashReader(utils.SHA1, reader)
+}
+
+// UploadDigestRange hashes the range of stream a sign check of rapid upload asks for
+func UploadDigestRange(stream model.FileStreamer, rangeSpec string) (string, error) {
+	r, err := signRange(rangeSpec, stream.GetSize())
 	if err != nil {
 		return "", err
 	}
-	result = strings.ToUpper(hashStr)
-	return
+	return rangeSHA1(stream, r)
+}
+
+const callbackRetries = 3
+
+// uploadByOSS picks the upload method by file size after rapid-upload missed,
+// the upload is tried again when 115 didn't register the uploaded object
+func (d *Pan115) uploadByOSS(ctx context.Context, params *driver115.UploadOSSParams, s model.FileStreamer, dirID string, up driver.UpdateProgress) (*UploadResult, error) {
+	for attempt := 1; ; attempt++ {
+		var (
+			res *UploadResult
+			err error
+		)
+		if s.GetSize() <= 10*utils.MB { // 文件大小小于10MB，改用普通模式上传
+			res, err = d.UploadByOSS(ctx, params, s, dirID, up)
+		} else {
+			// 分片上传
+			res, err = d.UploadByMultipart(ctx, params, s.GetSize(), s, dirID, up)
+		}
+		if err == nil || !errors.Is(err, ErrCallbackFailed) || attempt >= callbackRetries {
+			return res, err
+		}
+		logger(ctx).Warnf("upload %s: %v (attempt %d/%d)", s.GetName(), err, attempt, callbackRetries)
+	}
 }
 
 // UploadByOSS use aliyun sdk to upload
 func (c *Pan115) UploadByOSS(ctx context.Context, params *driver115.UploadOSSParams, s model.FileStreamer, dirID string, up driver.UpdateProgress) (*UploadResult, error) {
-	ossToken, err := c.client.GetOSSToken()
+	ossToken, err := c.getOSSToken()
 	if err != nil {
 		return nil, err
 	}
-	ossClient, err := oss.New(driver115.OSSEndpoint, ossToken.AccessKeyID, ossToken.AccessKeySecret)
+	ossClient, err := c.newOSSClient(ossToken)
 	if err != nil {
 		return nil, err
 	}
@@ -287,24 +648,72 @@ func (c *Pan115) UploadByOSS(ctx context.Context, params *driver115.UploadOSSPar
 	if err != nil {
 		return nil, err
 	}
+	// read from the cached file so that a retry can start over
+	tmpF, err := s.CacheFullInTempFile()
+	if err != nil {
+		return nil, err
+	}
 
 	var bodyBytes []byte
-	r := driver.NewLimitedUploadStream(ctx, &driver.ReaderUpdatingProgress{
-		Reader:         s,
-		UpdateProgress: up,
+	err = c.withOSSClock(ctx, "upload "+s.GetName(), func(opts ...oss.Option) error {
+		r := driver.NewLimitedUploadStream(ctx, &driver.ReaderUpdatingProgress{
+			Reader:         &driver.SimpleReaderWithSize{Reader: io.NewSectionReader(tmpF, 0, s.GetSize()), Size: s.GetSize()},
+			UpdateProgress: up,
+		})
+		opts = append(opts, oss.CallbackResult(&bodyBytes))
+		return bucket.PutObject(params.Object, r, append(driver115.OssOption(params, ossToken), opts...)...)
 	})
-	if err = bucket.PutObject(params.Object, r, append(
-		driver115.OssOption(params, ossToken),
-		oss.CallbackResult(&bodyBytes),
-	)...); err != nil {
+	return checkCallback(err, bodyBytes)
+}
+
+func (c *Pan115) newOSSClient(ossToken *driver115.UploadOSSTokenResp, opts ...oss.ClientOption) (*oss.Client, error) {
+	opts = append(opts, oss.HTTPClient(c.client.Load().Client.GetClient()))
+	return oss.New(driver115.OSSEndpoint, ossToken.AccessKeyID, ossToken.AccessKeySecret, opts...)
+}
+
+// checkCallback makes sure 115 registered the uploaded object,
+// oss answers 203 CallbackFailed when its callback to 115 failed
+func checkCallback(err error, bodyBytes []byte) (*UploadResult, error) {
+	var serviceErr oss.ServiceError
+	if errors.As(err, &serviceErr) && serviceErr.Code == "CallbackFailed" {
+		return nil, errors.Wrap(ErrCallbackFailed, serviceErr.Message)
+	}
+	if err != nil {
 		return nil, err
 	}
-
 	var uploadResult UploadResult
 	if err = json.Unmarshal(bodyBytes, &uploadResult); err != nil {
-		return nil, err
+		return nil, errors.Wrapf(ErrCallbackFailed, "bad callback result %q", bodyBytes)
+	}
+	if err = uploadResult.Err(string(bodyBytes)); err != nil {
+		return nil, errors.Wrap(ErrCallbackFailed, err.Error())
+	}
+	if uploadResult.Data.FileID == "" {
+		return nil, errors.Wrapf(ErrCallbackFailed, "no file id in callback result %q", bodyBytes)
+	}
+	return &uploadResult, nil
+}
+
+// maxUploadThreads caps how many parts of one upload go to oss at once
+const maxUploadThreads = 32
+
+type uploadThreadsKey struct{}
+
+// WithUploadThreads makes the multipart uploads under ctx send n parts at once,
+// taking precedence over the UploadThreads of the storage
+func WithUploadThreads(ctx context.Context, n int) context.Context {
+	return context.WithValue(ctx, uploadThreadsKey{}, n)
+}
+
+// uploadThreads is the part concurrency of the uploads under ctx
+func (d *Pan115) uploadThreads(ctx context.Context) int {
+	if n, ok := ctx.Value(uploadThreadsKey{}).(int); ok {
+		return n
 	}
-	return &uploadResult, uploadResult.Err(string(bodyBytes))
+	if d.UploadThreads > 0 {
+		return d.UploadThreads
+	}
+	return 1
 }
 
 // UploadByMultipart upload by mutipart blocks
@@ -327,19 +736,23 @@ func (d *Pan115) UploadByMultipart(ctx context.Context, params *driver115.Upload
 	}
 
 	options := driver115.DefalutUploadMultipartOptions()
+	options.ThreadsNum = d.uploadThreads(ctx)
 	if len(opts) > 0 {
 		for _, f := range opts {
 			f(options)
 		}
 	}
+	if options.ThreadsNum < 1 || options.ThreadsNum > maxUploadThreads {
+		return nil, errors.Errorf("upload threads must be between 1 and %d, got %d", maxUploadThreads, options.ThreadsNum)
+	}
 	// oss 启用Sequential必须按顺序上传
-	options.ThreadsNum = 1
+	sequential := options.ThreadsNum == 1
 
-	if ossToken, err = d.client.GetOSSToken(); err != nil {
+	if ossToken, err = d.getOSSToken(); err != nil {
 		return nil, err
 	}
 
-	if ossClient, err = oss.New(driver115.OSSEndpoint, ossToken.AccessKeyID, ossToken.AccessKeySecret, oss.EnableMD5(true), oss.EnableCRC(true)); err != nil {
+	if ossClient, err = d.newOSSClient(ossToken, oss.EnableMD5(true), oss.EnableCRC(true)); err != nil {
 		return nil, err
 	}
 
@@ -352,92 +765,130 @@ func (d *Pan115) UploadByMultipart(ctx context.Context, params *driver115.Upload
 	defer ticker.Stop()
 	// 设置超时
 	timeout := time.NewTimer(options.Timeout)
+	defer timeout.Stop()
 
-	if chunks, err = SplitFile(fileSize); err != nil {
+	if chunks, err = d.splitFile(ctx, fileSize); err != nil {
 		return nil, err
 	}
 
-	if imur, err = bucket.InitiateMultipartUpload(params.Object,
-		oss.SetHeader(driver115.OssSecurityTokenHeaderName, ossToken.SecurityToken),
-		oss.UserAgentHeader(driver115.OSSUserAgent),
-		oss.EnableSha1(), oss.Sequential(),
-	); err != nil {
+	if err = d.withOSSClock(ctx, "initiate multipart upload of "+s.GetName(), func(opts ...oss.Option) (err error) {
+		initOpts := []oss.Option{
+			oss.SetHeader(driver115.OssSecurityTokenHeaderName, ossToken.SecurityToken),
+			oss.UserAgentHeader(driver115.OSSUserAgent),
+		}
+		if sequential {
+			initOpts = append(initOpts, oss.EnableSha1(), oss.Sequential())
+		}
+		imur, err = bucket.InitiateMultipartUpload(params.Object, append(initOpts, opts...)...)
+		return err
+	}); err != nil {
 		return nil, err
 	}
-
-	wg := sync.WaitGroup{}
-	wg.Add(len(chunks))
+	tokenMu := sync.Mutex{}
+	completed := false
+	defer func() {
+		if completed {
+			return
+		}
+		tokenMu.Lock()
+		token := ossToken
+		tokenMu.Unlock()
+		// oss keeps the parts of an upload that is neither completed nor aborted
+		if err := bucket.AbortMultipartUpload(imur, oss.SetHeader(driver115.OssSecurityTokenHeaderName, token.SecurityToken),
+			oss.UserAgentHeader(driver115.OSSUserAgent)); err != nil {
+			logger(ctx).Warnf("abort the multipart upload of %s failed: %v", s.GetName(), err)
+		}
+	}()
+	// stops the producer and the consumers once the upload returns, whatever the way
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
 
 	chunksCh := make(chan oss.FileChunk)
 	errCh := make(chan error)
 	UploadedPartsCh := make(chan oss.UploadPart)
-	quit := make(chan struct{})
 
 	// producer
-	go chunksProducer(chunksCh, chunks)
-	go func() {
-		wg.Wait()
-		quit <- struct{}{}
-	}()
+	go chunksProducer(ctx, chunksCh, chunks)
 
 	completedNum := atomic.Int32{}
+	uploadPart := func(chunk oss.FileChunk) (part oss.UploadPart, err error) {
+		tokenMu.Lock()
+		token := ossToken
+		tokenMu.Unlock()
+		buf := make([]byte, chunk.Size)
+		if _, err := tmpF.ReadAt(buf, chunk.Offset); err != nil && !errors.Is(err, io.EOF) {
+			return part, err
+		}
+		err = d.withOSSClock(ctx, fmt.Sprintf("upload part %d of %s", chunk.Number, s.GetName()), func(opts ...oss.Option) (err error) {
+			part, err = bucket.UploadPart(imur, driver.NewLimitedUploadStream(ctx, bytes.NewReader(buf)),
+				chunk.Size, chunk.Number, append(driver115.OssOption(params, token), opts...)...)
+			return err
+		})
+		return part, err
+	}
 	// consumers
 	for i := 0; i < options.ThreadsNum; i++ {
 		go func(threadId int) {
 			defer func() {
 				if r := recover(); r != nil {
-					errCh <- fmt.Errorf("recovered in %v", r)
+					select {
+					case errCh <- fmt.Errorf("recovered in %v", r):
+					case <-ctx.Done():
+					}
 				}
 			}()
 			for chunk := range chunksCh {
 				var part oss.UploadPart // 出现错误就继续尝试，共尝试3次
-				for retry := 0; retry < 3; retry++ {
+				err := retry(ctx, 3, fmt.Sprintf("upload part %d of %s", chunk.Number, s.GetName()), func() error {
 					select {
 					case <-ctx.Done():
-						break
+						return ctx.Err()
 					case <-ticker.C:
-						if ossToken, err = d.client.GetOSSToken(); err != nil { // 到时重新获取ossToken
-							errCh <- errors.Wrap(err, "刷新token时出现错误")
+						token, err := d.getOSSToken() // 到时重新获取ossToken
+						if err != nil {
+							return errors.Wrap(err, "刷新token时出现错误")
 						}
+						tokenMu.Lock()
+						ossToken = token
+						tokenMu.Unlock()
 					default:
 					}
-					buf := make([]byte, chunk.Size)
-					if _, err = tmpF.ReadAt(buf, chunk.Offset); err != nil && !errors.Is(err, io.EOF) {
-						continue
-					}
-					if part, err = bucket.UploadPart(imur, driver.NewLimitedUploadStream(ctx, bytes.NewReader(buf)),
-						chunk.Size, chunk.Number, driver115.OssOption(params, ossToken)...); err == nil {
-						break
+					var err error
+					part, err = uploadPart(chunk)
+					return err
+				})
+				if err != nil {
+					select {
+					case errCh <- errors.Wrap(err, fmt.Sprintf("上传 %s 的第%d个分片时出现错误：%v", s.GetName(), chunk.Number, err)):
+					case <-ctx.Done():
 					}
+					return
 				}
-				if err != nil {
-					errCh <- errors.Wrap(err, fmt.Sprintf("上传 %s 的第%d个分片时出现错误：%v", s.GetName(), chunk.Number, err))
-				} else {
-					num := completedNum.Add(1)
-					up(float64(num) * 100.0 / float64(len(chunks)))
+				num := completedNum.Add(1)
+				up(float64(num) * 100.0 / float64(len(chunks)))
+				select {
+				case UploadedPartsCh <- part:
+				case <-ctx.Done():
+					return
 				}
-				UploadedPartsCh <- part
 			}
 		}(i)
 	}
 
-	go func() {
-		for part := range UploadedPartsCh {
-			parts = append(parts, part)
-			wg.Done()
-		}
-	}()
-LOOP:
-	for {
+	for len(parts) < len(chunks) {
 		select {
 		case <-ticker.C:
 			// 到时重新获取ossToken
-			if ossToken, err = d.client.GetOSSToken(); err != nil {
+			token, err := d.getOSSToken()
+			if err != nil {
 				return nil, err
 			}
-		case <-quit:
-			break LOOP
-		case <-errCh:
+			tokenMu.Lock()
+			ossToken = token
+			tokenMu.Unlock()
+		case part := <-UploadedPartsCh:
+			parts = append(parts, part)
+		case err := <-errCh:
 			return nil, err
 		case <-timeout.C:
 			return nil, fmt.Errorf("time out")
@@ -446,24 +897,70 @@ LOOP:
 
 	// 不知道啥原因，oss那边分片上传不计算sha1，导致115服务器校验错误
 	// params.Callback.Callback = strings.ReplaceAll(params.Callback.Callback, "${sha1}", params.SHA1)
-	if _, err := bucket.CompleteMultipartUpload(imur, parts, append(
-		driver115.OssOption(params, ossToken),
-		oss.CallbackResult(&bodyBytes),
-	)...); err != nil {
-		return nil, err
+	complete := func() error {
+		return d.withOSSClock(ctx, "complete multipart upload of "+s.GetName(), func(opts ...oss.Option) error {
+			opts = append(opts, oss.CallbackResult(&bodyBytes))
+			_, err := bucket.CompleteMultipartUpload(imur, parts, append(driver115.OssOption(params, ossToken), opts...)...)
+			return err
+		})
+	}
+	err = complete()
+	if isInvalidPart(err) && d.RepairParts {
+		// a part got corrupted on the way, upload the parts oss holds differently and complete again
+		logger(ctx).Warnf("oss refused the parts of %s, checking them: %v", s.GetName(), err)
+		if parts, err = d.repairParts(ctx, bucket, imur, ossToken, parts, chunks, uploadPart); err == nil {
+			err = complete()
+		}
 	}
+	completed = err == nil
+	return checkCallback(err, bodyBytes)
+}
 
-	var uploadResult UploadResult
-	if err = json.Unmarshal(bodyBytes, &uploadResult); err != nil {
+func chunksProducer(ctx context.Context, ch chan oss.FileChunk, chunks []oss.FileChunk) {
+	for _, chunk := range chunks {
+		select {
+		case ch <- chunk:
+		case <-ctx.Done():
+			return
+		}
+	}
+	close(ch)
+}
+
+const (
+	maxOSSParts    = 10000
+	minOSSPartSize = 100 * utils.KB
+	maxOSSPartSize = 5 * utils.GB
+)
+
+// splitFile splits an upload into parts of UploadPartSize, or leaves the part size to
+// SplitFile when it isn't set
+func (d *Pan115) splitFile(ctx context.Context, fileSize int64) ([]oss.FileChunk, error) {
+	if d.UploadPartSize <= 0 {
+		return SplitFile(fileSize)
+	}
+	partSize, err := fitPartSize(fileSize, d.UploadPartSize*utils.MB)
+	if err != nil {
 		return nil, err
 	}
-	return &uploadResult, uploadResult.Err(string(bodyBytes))
+	if partSize != d.UploadPartSize*utils.MB {
+		logger(ctx).Infof("parts of %d MB don't fit %d bytes in %d parts, using parts of %d bytes",
+			d.UploadPartSize, fileSize, maxOSSParts, partSize)
+	}
+	return SplitFileByPartSize(fileSize, partSize)
 }
 
-func chunksProducer(ch chan oss.FileChunk, chunks []oss.FileChunk) {
-	for _, chunk := range chunks {
-		ch <- chunk
+// fitPartSize keeps partSize within the oss limits, raising it when fileSize
+// would need more than maxOSSParts parts
+func fitPartSize(fileSize, partSize int64) (int64, error) {
+	partSize = min(max(partSize, minOSSPartSize), maxOSSPartSize)
+	if need := (fileSize + maxOSSParts - 1) / maxOSSParts; need > partSize {
+		partSize = need
+	}
+	if partSize > maxOSSPartSize {
+		return 0, errors.Errorf("%d bytes don't fit in %d parts of at most %d bytes", fileSize, maxOSSParts, maxOSSPartSize)
 	}
+	return partSize, nil
 }
 
 func SplitFile(fileSize int64) (chunks []oss.FileChunk, err error) {
@@ -481,8 +978,8 @@ func SplitFile(fileSize int64) (chunks []oss.FileChunk, err error) {
 		}
 	}
 	// 单个分片大小不能小于100KB
-	if chunks[0].Size < 100*utils.KB {
-		if chunks, err = SplitFileByPartSize(fileSize, 100*utils.KB); err != nil {
+	if chunks[0].Size < minOSSPartSize {
+		if chunks, err = SplitFileByPartSize(fileSize, minOSSPartSize); err != nil {
 			return
 		}
 	}
@@ -525,7 +1022,7 @@ func SplitFileByPartSize(fileSize int64, chunkSize int64) ([]oss.FileChunk, erro
 	}
 
 	chunkN := fileSize / chunkSize
-	if chunkN >= 10000 {
+	if (fileSize+chunkSize-1)/chunkSize > maxOSSParts {
 		return nil, errors.New("Too many parts, please increase part size")
 	}
 
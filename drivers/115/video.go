@@ -0,0 +1,132 @@
+package _115
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/alist-org/alist/v3/internal/conf"
+	"github.com/alist-org/alist/v3/internal/model"
+	"github.com/alist-org/alist/v3/pkg/utils"
+	"github.com/pkg/errors"
+)
+
+const apiVideoM3U8 = "https://115.com/api/video/m3u8/%s.m3u8"
+
+type VideoQuality struct {
+	Quality string `json:"quality"`
+	Height  int    `json:"height"`
+	URL     string `json:"url"`
+}
+
+type VideoPlayInfo struct {
+	Selected  *VideoQuality  `json:"selected"`
+	Qualities []VideoQuality `json:"qualities"`
+}
+
+// GetVideoPlayInfo gets the transcoded play urls of a video, the preferred quality is selected
+// and every available quality is returned so the user can switch
+func (d *Pan115) GetVideoPlayInfo(ctx context.Context, pickCode string) (*VideoPlayInfo, error) {
+	if err := d.WaitLimit(ctx); err != nil {
+		return nil, err
+	}
+	resp, err := d.client.Load().Client.R().
+		SetContext(ctx).
+		Get(fmt.Sprintf(apiVideoM3U8, pickCode))
+	if err != nil {
+		return nil, err
+	}
+	qualities := parseM3U8Qualities(resp.String())
+	if len(qualities) == 0 {
+		return nil, ErrTranscoding
+	}
+	return &VideoPlayInfo{
+		Selected:  selectQuality(qualities, d.PreferredQuality),
+		Qualities: qualities,
+	}, nil
+}
+
+// playLink links to the transcoded stream of file in the preferred quality,
+// for videos that can only be played and not downloaded
+func (d *Pan115) playLink(ctx context.Context, file model.Obj, userAgent string) (*model.Link, error) {
+	info, err := d.GetVideoPlayInfo(ctx, file.(*FileObj).PickCode)
+	if err != nil {
+		return nil, errors.Wrap(ErrProtectedContent, err.Error())
+	}
+	logger(ctx).Debugf("%s is protected, previewing %s stream", file.GetName(), info.Selected.Quality)
+	return &model.Link{
+		URL: info.Selected.URL,
+		Header: http.Header{
+			"Cookie":     []string{d.Cookie},
+			"User-Agent": []string{userAgent},
+		},
+	}, nil
+}
+
+// previewTranscoded is whether previews of file play the transcoded stream in the preferred
+// quality, with auto they play the original file
+func (d *Pan115) previewTranscoded(file model.Obj) bool {
+	_, ok := qualityHeight(d.PreferredQuality)
+	return ok && utils.GetFileType(file.GetName()) == conf.VIDEO
+}
+
+// parseM3U8Qualities reads the variants of a master playlist, highest quality first
+func parseM3U8Qualities(playlist string) []VideoQuality {
+	var qualities []VideoQuality
+	var pending *VideoQuality
+	scanner := bufio.NewScanner(strings.NewReader(playlist))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case strings.HasPrefix(line, "#EXT-X-STREAM-INF:"):
+			pending = &VideoQuality{}
+			for _, attr := range strings.Split(strings.TrimPrefix(line, "#EXT-X-STREAM-INF:"), ",") {
+				k, v, _ := strings.Cut(attr, "=")
+				if k != "RESOLUTION" {
+					continue
+				}
+				if _, h, ok := strings.Cut(v, "x"); ok {
+					pending.Height, _ = strconv.Atoi(h)
+				}
+			}
+			pending.Quality = fmt.Sprintf("%dp", pending.Height)
+		case line != "" && !strings.HasPrefix(line, "#") && pending != nil:
+			pending.URL = line
+			qualities = append(qualities, *pending)
+			pending = nil
+		}
+	}
+	sort.SliceStable(qualities, func(i, j int) bool {
+		return qualities[i].Height > qualities[j].Height
+	})
+	return qualities
+}
+
+// selectQuality picks the preferred quality, or the next lower one when it isn't ready.
+// A preference lower than everything available picks the lowest one, auto and anything
+// that isn't a height like 720p pick the highest one.
+func selectQuality(qualities []VideoQuality, preferred string) *VideoQuality {
+	if len(qualities) == 0 {
+		return nil
+	}
+	height, ok := qualityHeight(preferred)
+	if !ok {
+		return &qualities[0]
+	}
+	for i := range qualities {
+		if qualities[i].Height <= height {
+			return &qualities[i]
+		}
+	}
+	return &qualities[len(qualities)-1]
+}
+
+// qualityHeight reads the height of a quality like 720p, ok is false for auto
+func qualityHeight(quality string) (int, bool) {
+	height, err := strconv.Atoi(strings.TrimSuffix(quality, "p"))
+	return height, err == nil && height > 0
+}
@@ -0,0 +1,85 @@
+package _115
+
+import (
+	"context"
+	"iter"
+	stdpath "path"
+
+	"github.com/pkg/errors"
+)
+
+const defaultMaxDepth = 64
+
+// errStopWalk ends a walk early without failing it
+var errStopWalk = errors.New("stop walk")
+
+// walkFunc is called for every entry under the walked folder,
+// dirPath is the path of its parent relative to the walked folder
+type walkFunc func(dirPath string, f *FileObj) error
+
+// walk visits the subtree of dirID depth first, folders are listed at most once
+// and going deeper than MaxDepth fails with ErrMaxDepthExceeded.
+func (d *Pan115) walk(ctx context.Context, dirID string, fn walkFunc) error {
+	maxDepth := d.MaxDepth
+	if maxDepth <= 0 {
+		maxDepth = defaultMaxDepth
+	}
+	visited := map[string]struct{}{dirID: {}}
+	var walkDir func(dirID, dirPath string, depth int) error
+	walkDir = func(dirID, dirPath string, depth int) error {
+		if depth > maxDepth {
+			return errors.Wrapf(ErrMaxDepthExceeded, "%s (max %d)", dirPath, maxDepth)
+		}
+		if err := d.WaitLimit(ctx); err != nil {
+			return err
+		}
+		files, err := d.getFiles(ctx, dirID)
+		if err != nil {
+			return err
+		}
+		for i := range files {
+			f := &files[i]
+			if err := fn(dirPath, f); err != nil {
+				return err
+			}
+			if !f.IsDir() {
+				continue
+			}
+			if _, ok := visited[f.GetID()]; ok {
+				return errors.Wrap(ErrCyclicDir, stdpath.Join(dirPath, f.GetName()))
+			}
+			visited[f.GetID()] = struct{}{}
+			if err := walkDir(f.GetID(), stdpath.Join(dirPath, f.GetName()), depth+1); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return walkDir(dirID, "/", 1)
+}
+
+// ListRecursive yields every file under dirID with its path relative to dirID, folder by folder
+// as they are listed, so only the folders on the current path are held at a time. It is bound by
+// MaxDepth and the rate limit like walk, stops when ctx is done, and the returned func reports
+// the error that ended it early, if any, once the iteration is over.
+func (d *Pan115) ListRecursive(ctx context.Context, dirID string) (iter.Seq2[string, *FileObj], func() error) {
+	var err error
+	seq := func(yield func(string, *FileObj) bool) {
+		err = d.walk(ctx, dirID, func(dirPath string, f *FileObj) error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if f.IsDir() {
+				return nil
+			}
+			if !yield(stdpath.Join(dirPath, f.GetName()), f) {
+				return errStopWalk
+			}
+			return nil
+		})
+		if errors.Is(err, errStopWalk) {
+			err = nil
+		}
+	}
+	return seq, func() error { return err }
+}
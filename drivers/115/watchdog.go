@@ -0,0 +1,91 @@
+package _115
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/pkg/errors"
+)
+
+// watchdogCooldown is the least time between two rebuilds of the client
+const watchdogCooldown = 10 * time.Minute
+
+// watchdog rebuilds the client after a run of failed requests that neither 115
+// nor the login explains, like a transport that stopped working
+type watchdog struct {
+	mu        sync.Mutex
+	failures  int
+	rebuiltAt time.Time
+}
+
+// ok ends the current run of failures
+func (w *watchdog) ok() {
+	w.mu.Lock()
+	w.failures = 0
+	w.mu.Unlock()
+}
+
+// fail counts err and calls rebuild once threshold failures in a row are reached,
+// at most once every watchdogCooldown. A threshold of 0 turns the watchdog off.
+func (w *watchdog) fail(ctx context.Context, err error, threshold int, rebuild func() error) {
+	if threshold <= 0 || !isWedgeErr(err) {
+		return
+	}
+	w.mu.Lock()
+	w.failures++
+	if w.failures < threshold || time.Since(w.rebuiltAt) < watchdogCooldown {
+		w.mu.Unlock()
+		return
+	}
+	w.failures = 0
+	last := w.rebuiltAt
+	w.rebuiltAt = time.Now()
+	w.mu.Unlock()
+	logger(ctx).Warnf("%d requests failed in a row, rebuilding the 115 client: %v", threshold, err)
+	err = rebuild()
+	if errors.Is(err, errReloginBusy) {
+		// the login going on makes a new client anyway, a later run may still rebuild
+		w.mu.Lock()
+		w.rebuiltAt = last
+		w.mu.Unlock()
+		return
+	}
+	if err != nil {
+		logger(ctx).Errorf("rebuild the 115 client failed: %v", err)
+	}
+}
+
+// isWedgeErr leaves out the errors that are handled elsewhere: canceled calls,
+// expired logins, rate limits, pauses and errors about the file itself. An error
+// that came with a response was answered, so the transport still works.
+func isWedgeErr(err error) bool {
+	var respErr *resty.ResponseError
+	return err != nil &&
+		!errors.As(err, &respErr) &&
+		!errors.Is(err, context.Canceled) &&
+		!errors.Is(err, context.DeadlineExceeded) &&
+		!errors.Is(err, ErrAuthExpired) &&
+		!errors.Is(err, ErrRateLimited) &&
+		!isPauseErr(err) &&
+		!isNoRetry(err)
+}
+
+// errReloginBusy is returned by rebuildClient while a login is going on already
+var errReloginBusy = errors.New("a login is in progress")
+
+// rebuildClient logs in again from scratch, with a new client and transport. It can run
+// from a request made by a login itself, so it gives up instead of waiting for reloginMu.
+func (d *Pan115) rebuildClient() error {
+	if !d.reloginMu.TryLock() {
+		return errReloginBusy
+	}
+	defer d.reloginMu.Unlock()
+	if err := d.login(); err != nil {
+		return err
+	}
+	d.ossTokens.Del(ossTokenKey)
+	d.reloginAt = time.Now()
+	return nil
+}
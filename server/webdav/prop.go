@@ -469,11 +469,19 @@ type ETager interface {
 }
 
 func findETag(ctx context.Context, ls LockSystem, name string, fi model.Obj) (string, error) {
-	if do, ok := fi.(ETager); ok {
-		etag, err := do.ETag(ctx)
-		if !errors.Is(err, ErrNotImplemented) {
-			return etag, err
+	// storages supply their own ETags on the objects that alist wraps
+	for obj := fi; ; {
+		if do, ok := obj.(ETager); ok {
+			etag, err := do.ETag(ctx)
+			if !errors.Is(err, ErrNotImplemented) {
+				return etag, err
+			}
+		}
+		unwrap, ok := obj.(model.ObjUnwrap)
+		if !ok {
+			break
 		}
+		obj = unwrap.Unwrap()
 	}
 	// The Apache http 2.4 web server by default concatenates the
 	// modification time and size of a file. We replicate the heuristic